@@ -0,0 +1,55 @@
+package api
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff calcula esperas exponenciais com jitter entre tentativas de reconexão
+type Backoff struct {
+	Min    time.Duration
+	Max    time.Duration
+	Factor float64
+	// Jitter é a fração (0 a 1) da espera que pode variar aleatoriamente
+	Jitter float64
+
+	attempt int
+}
+
+// NewBackoff cria um Backoff com os valores padrão (500ms até 30s, dobrando a cada tentativa)
+func NewBackoff() *Backoff {
+	return &Backoff{
+		Min:    500 * time.Millisecond,
+		Max:    30 * time.Second,
+		Factor: 2,
+		Jitter: 0.5,
+	}
+}
+
+// Next retorna a espera da próxima tentativa e avança o contador
+func (b *Backoff) Next() time.Duration {
+	d := float64(b.Min)
+	for i := 0; i < b.attempt && d < float64(b.Max); i++ {
+		d *= b.Factor
+	}
+	if d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	b.attempt++
+
+	if b.Jitter > 0 {
+		// Espalha as tentativas em [d*(1-Jitter), d] para evitar reconexões simultâneas
+		d -= d * b.Jitter * rand.Float64()
+	}
+	return time.Duration(d)
+}
+
+// Attempt retorna quantas esperas já foram calculadas desde o último Reset
+func (b *Backoff) Attempt() int {
+	return b.attempt
+}
+
+// Reset volta o Backoff ao estado inicial após uma conexão bem-sucedida
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffGrowsUpToMax(t *testing.T) {
+	b := &Backoff{Min: 100 * time.Millisecond, Max: time.Second, Factor: 2}
+	want := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for i, w := range want {
+		if got := b.Next(); got != w*time.Millisecond {
+			t.Errorf("tentativa %d: espera %v, esperado %v", i, got, w*time.Millisecond)
+		}
+	}
+	if b.Attempt() != len(want) {
+		t.Errorf("Attempt = %d, esperado %d", b.Attempt(), len(want))
+	}
+
+	b.Reset()
+	if got := b.Next(); got != 100*time.Millisecond {
+		t.Errorf("após Reset: espera %v, esperado 100ms", got)
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	b := NewBackoff()
+	for i := 0; i < 20; i++ {
+		b.Reset()
+		d := b.Next()
+		if d < b.Min/2 || d > b.Min {
+			t.Fatalf("espera %v fora de [%v, %v]", d, b.Min/2, b.Min)
+		}
+	}
+}
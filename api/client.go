@@ -3,15 +3,32 @@ package api
 import (
 	"github.com/mellojp/chatli/data"
 
+	"github.com/gorilla/websocket"
+
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var API_URL string
 
+const (
+	// DefaultTimeout é o prazo padrão de uma requisição HTTP quando o contexto não define um
+	DefaultTimeout = 10 * time.Second
+	// DefaultDialTimeout é o prazo padrão para abrir a conexão de websocket
+	DefaultDialTimeout = 15 * time.Second
+	// DefaultUserAgent identifica o chatli nas requisições
+	DefaultUserAgent = "chatli"
+)
+
 func getAPIURL() string {
 	if API_URL != "" {
 		return API_URL
@@ -23,22 +40,121 @@ func getAPIURL() string {
 	return val
 }
 
-// Login realiza a autenticação e retorna a sessão com o token JWT
-func Login(username, password string) (*data.Session, error) {
-	msg := map[string]string{
-		"username": username,
-		"password": password,
+// Client concentra a configuração de acesso a um servidor chatli.
+// Vários clients podem coexistir no mesmo processo apontando para servidores diferentes.
+type Client struct {
+	BaseURL    string
+	WSURL      string
+	HTTPClient *http.Client
+	// Dialer abre o websocket; nil usa websocket.DefaultDialer
+	Dialer    *websocket.Dialer
+	UserAgent string
+	Session   data.Session
+
+	// Timeout é aplicado às chamadas cujo contexto não tem deadline (0 desativa)
+	Timeout time.Duration
+	// DialTimeout é aplicado à abertura do websocket cujo contexto não tem deadline (0 desativa)
+	DialTimeout time.Duration
+	// Heartbeat e SendQueue configuram as conexões abertas com Connect
+	Heartbeat Heartbeat
+	SendQueue int
+}
+
+// NewClient cria um Client para a URL base informada com timeouts e user agent padrão.
+// A URL do websocket é derivada da URL base (http→ws, https→wss).
+func NewClient(baseURL string) *Client {
+	baseURL = strings.TrimRight(baseURL, "/")
+	return &Client{
+		BaseURL:    baseURL,
+		WSURL:      DeriveWSURL(baseURL),
+		HTTPClient: &http.Client{},
+		// Mesmo proxy do ambiente (HTTPS_PROXY/NO_PROXY) usado pelo transporte HTTP padrão
+		Dialer: &websocket.Dialer{
+			Proxy:            dialerProxy(http.ProxyFromEnvironment),
+			HandshakeTimeout: websocket.DefaultDialer.HandshakeTimeout,
+		},
+		UserAgent:   DefaultUserAgent,
+		Timeout:     DefaultTimeout,
+		DialTimeout: DefaultDialTimeout,
+		Heartbeat:   DefaultHeartbeat(),
+		SendQueue:   DefaultSendQueue,
+	}
+}
+
+// DefaultClient retorna um Client configurado a partir de API_URL e WS_URL.
+// Sem WS_URL, o websocket usa o mesmo servidor da API.
+func DefaultClient() *Client {
+	apiURL := getAPIURL()
+	c := NewClient(apiURL)
+	c.WSURL = strings.TrimRight(getWSURL(apiURL), "/")
+	return c
+}
+
+func clientFor(s data.Session) *Client {
+	c := DefaultClient()
+	c.Session = s
+	return c
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// withDeadline aplica o timeout padrão quando o contexto recebido não tem prazo
+func withDeadline(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || d <= 0 {
+		return context.WithCancel(ctx)
 	}
-	body, _ := json.Marshal(msg)
+	return context.WithTimeout(ctx, d)
+}
+
+// do monta a requisição com os headers comuns (auth, user agent e content type) e a executa
+func (c *Client) do(ctx context.Context, method, path string, payload any) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	if c.Session.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Session.Token)
+	}
+	return c.httpClient().Do(req)
+}
+
+// LoginContext realiza a autenticação e retorna a sessão com o token JWT.
+// A sessão também passa a ser usada pelo Client nas chamadas seguintes.
+func (c *Client) LoginContext(ctx context.Context, username, password string) (*data.Session, error) {
+	ctx, cancel := withDeadline(ctx, c.Timeout)
+	defer cancel()
 
-	resp, err := http.Post(getAPIURL()+"/auth/login", "application/json", bytes.NewBuffer(body))
+	resp, err := c.do(ctx, "POST", "/auth/login", map[string]string{
+		"username": username,
+		"password": password,
+	})
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("login falhou: status %d", resp.StatusCode)
+		return nil, newError("login falhou", "/auth/login", resp)
 	}
 
 	var res map[string]string
@@ -52,48 +168,92 @@ func Login(username, password string) (*data.Session, error) {
 		UserId:      res["user_id"],
 		JoinedRooms: []data.Room{},
 	}
+	c.Session = *session
 	return session, nil
 }
 
-// Register cria um novo usuário
-func Register(username, password string) error {
-	msg := map[string]string{
-		"username": username,
-		"password": password,
+// RefreshContext troca o token da sessão por um novo antes que ele expire.
+// Servidores sem o endpoint respondem 404 ou 405, o que pode ser testado com RefreshUnsupported.
+func (c *Client) RefreshContext(ctx context.Context) (*data.Session, error) {
+	ctx, cancel := withDeadline(ctx, c.Timeout)
+	defer cancel()
+
+	resp, err := c.do(ctx, "POST", "/auth/refresh", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, newError("renovação do token falhou", "/auth/refresh", resp)
+	}
+
+	var res map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
 	}
-	body, _ := json.Marshal(msg)
+	if res["token"] == "" {
+		return nil, errors.New("renovação do token falhou: resposta sem token")
+	}
+
+	session := c.Session
+	session.Token = res["token"]
+	c.Session = session
+	return &session, nil
+}
 
-	resp, err := http.Post(getAPIURL()+"/auth/register", "application/json", bytes.NewBuffer(body))
+// LogoutContext invalida o token da sessão no servidor e limpa a sessão do Client.
+// A sessão local é limpa mesmo que a chamada falhe.
+func (c *Client) LogoutContext(ctx context.Context) error {
+	ctx, cancel := withDeadline(ctx, c.Timeout)
+	defer cancel()
+	defer func() { c.Session = data.Session{} }()
+
+	resp, err := c.do(ctx, "POST", "/auth/logout", nil)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != 201 {
-		return fmt.Errorf("registro falhou: status %d", resp.StatusCode)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newError("logout falhou", "/auth/logout", resp)
 	}
 	return nil
 }
 
-// CreateRoom cria uma nova sala enviando o nome desejado
-func CreateRoom(s data.Session, roomName string) (*data.Room, error) {
-	reqUrl := getAPIURL() + "/rooms/create"
-	payload := map[string]string{"room_name": roomName}
-	body, _ := json.Marshal(payload)
+// RegisterContext cria um novo usuário
+func (c *Client) RegisterContext(ctx context.Context, username, password string) error {
+	ctx, cancel := withDeadline(ctx, c.Timeout)
+	defer cancel()
+
+	resp, err := c.do(ctx, "POST", "/auth/register", map[string]string{
+		"username": username,
+		"password": password,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 201 {
+		return newError("registro falhou", "/auth/register", resp)
+	}
+	return nil
+}
 
-	req, _ := http.NewRequest("POST", reqUrl, bytes.NewBuffer(body))
-	req.Header.Add("Authorization", "Bearer "+s.Token)
-	req.Header.Add("Content-Type", "application/json")
+// CreateRoomContext cria uma nova sala enviando o nome desejado
+func (c *Client) CreateRoomContext(ctx context.Context, roomName string) (*data.Room, error) {
+	ctx, cancel := withDeadline(ctx, c.Timeout)
+	defer cancel()
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(ctx, "POST", "/rooms/create", map[string]string{"room_name": roomName})
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("erro ao criar sala: status %d", resp.StatusCode)
+		return nil, newError("erro ao criar sala", "/rooms/create", resp)
 	}
 
 	var res data.Room
@@ -101,43 +261,36 @@ func CreateRoom(s data.Session, roomName string) (*data.Room, error) {
 	return &res, nil
 }
 
-func JoinRoom(s data.Session, roomId string) error {
-	reqUrl := getAPIURL() + "/rooms/join"
-	payload := map[string]string{"room_id": roomId}
-	body, _ := json.Marshal(payload)
-
-	req, _ := http.NewRequest("POST", reqUrl, bytes.NewBuffer(body))
-	req.Header.Add("Authorization", "Bearer "+s.Token)
-	req.Header.Add("Content-Type", "application/json")
+// JoinRoomContext adiciona o usuário da sessão à sala informada
+func (c *Client) JoinRoomContext(ctx context.Context, roomId string) error {
+	ctx, cancel := withDeadline(ctx, c.Timeout)
+	defer cancel()
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(ctx, "POST", "/rooms/join", map[string]string{"room_id": roomId})
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 201 && resp.StatusCode != 200 {
-		return fmt.Errorf("erro ao entrar na sala: status %d", resp.StatusCode)
+		return newError("erro ao entrar na sala", "/rooms/join", resp)
 	}
 	return nil
 }
 
-// GetUserRooms busca as salas que o usuário já entrou
-func GetUserRooms(s data.Session) ([]data.Room, error) {
-	reqUrl := getAPIURL() + "/rooms"
-	req, _ := http.NewRequest("GET", reqUrl, nil)
-	req.Header.Add("Authorization", "Bearer "+s.Token)
+// GetUserRoomsContext busca as salas que o usuário já entrou
+func (c *Client) GetUserRoomsContext(ctx context.Context) ([]data.Room, error) {
+	ctx, cancel := withDeadline(ctx, c.Timeout)
+	defer cancel()
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(ctx, "GET", "/rooms", nil)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("erro ao buscar salas: status %d", resp.StatusCode)
+		return nil, newError("erro ao buscar salas", "/rooms", resp)
 	}
 
 	var rooms []data.Room
@@ -147,21 +300,56 @@ func GetUserRooms(s data.Session) ([]data.Room, error) {
 	return rooms, nil
 }
 
-func LoadChatMessages(s data.Session, roomId string) ([]data.Message, error) {
-	reqUrl := fmt.Sprintf("%s/rooms/history?room_id=%s", getAPIURL(), roomId)
-	req, _ := http.NewRequest("GET", reqUrl, nil)
-	req.Header.Add("Authorization", "Bearer "+s.Token)
+// LoadChatMessagesContext busca o histórico completo de uma sala
+func (c *Client) LoadChatMessagesContext(ctx context.Context, roomId string) ([]data.Message, error) {
+	ctx, cancel := withDeadline(ctx, c.Timeout)
+	defer cancel()
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(ctx, "GET", "/rooms/history?room_id="+url.QueryEscape(roomId), nil)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
+
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("erro na requisição: %d", resp.StatusCode)
+		return nil, newError("erro ao carregar histórico", "/rooms/history", resp)
+	}
+
+	var messages []data.Message
+	if err := json.NewDecoder(resp.Body).Decode(&messages); err != nil {
+		return nil, err
+	}
+	if messages == nil {
+		messages = []data.Message{}
+	}
+	return messages, nil
+}
+
+// LoadChatMessagesPageContext busca uma página do histórico de uma sala: até limit mensagens
+// anteriores à mensagem com Id before (ou as mais recentes, se before for vazio), em ordem cronológica
+func (c *Client) LoadChatMessagesPageContext(ctx context.Context, roomId, before string, limit int) ([]data.Message, error) {
+	ctx, cancel := withDeadline(ctx, c.Timeout)
+	defer cancel()
+
+	query := url.Values{}
+	query.Set("room_id", roomId)
+	if before != "" {
+		query.Set("before", before)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	resp, err := c.do(ctx, "GET", "/rooms/history?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != 200 {
+		return nil, newError("erro ao carregar histórico", "/rooms/history", resp)
+	}
+
 	var messages []data.Message
 	if err := json.NewDecoder(resp.Body).Decode(&messages); err != nil {
 		return nil, err
@@ -171,3 +359,121 @@ func LoadChatMessages(s data.Session, roomId string) ([]data.Message, error) {
 	}
 	return messages, nil
 }
+
+// Login realiza a autenticação e retorna a sessão com o token JWT
+func (c *Client) Login(username, password string) (*data.Session, error) {
+	return c.LoginContext(context.Background(), username, password)
+}
+
+// Refresh troca o token da sessão por um novo antes que ele expire
+func (c *Client) Refresh() (*data.Session, error) {
+	return c.RefreshContext(context.Background())
+}
+
+// Register cria um novo usuário
+func (c *Client) Register(username, password string) error {
+	return c.RegisterContext(context.Background(), username, password)
+}
+
+// CreateRoom cria uma nova sala enviando o nome desejado
+func (c *Client) CreateRoom(roomName string) (*data.Room, error) {
+	return c.CreateRoomContext(context.Background(), roomName)
+}
+
+// JoinRoom adiciona o usuário da sessão à sala informada
+func (c *Client) JoinRoom(roomId string) error {
+	return c.JoinRoomContext(context.Background(), roomId)
+}
+
+// GetUserRooms busca as salas que o usuário já entrou
+func (c *Client) GetUserRooms() ([]data.Room, error) {
+	return c.GetUserRoomsContext(context.Background())
+}
+
+// LoadChatMessages busca o histórico completo de uma sala
+func (c *Client) LoadChatMessages(roomId string) ([]data.Message, error) {
+	return c.LoadChatMessagesContext(context.Background(), roomId)
+}
+
+// LoadChatMessagesPage busca uma página do histórico de uma sala
+func (c *Client) LoadChatMessagesPage(roomId, before string, limit int) ([]data.Message, error) {
+	return c.LoadChatMessagesPageContext(context.Background(), roomId, before, limit)
+}
+
+// Login realiza a autenticação e retorna a sessão com o token JWT
+func Login(username, password string) (*data.Session, error) {
+	return DefaultClient().Login(username, password)
+}
+
+// LoginContext é a variante de Login que respeita o cancelamento do contexto
+func LoginContext(ctx context.Context, username, password string) (*data.Session, error) {
+	return DefaultClient().LoginContext(ctx, username, password)
+}
+
+// RefreshContext troca o token da sessão por um novo antes que ele expire
+func RefreshContext(ctx context.Context, s data.Session) (*data.Session, error) {
+	return clientFor(s).RefreshContext(ctx)
+}
+
+// LogoutContext invalida o token da sessão no servidor
+func LogoutContext(ctx context.Context, s data.Session) error {
+	return clientFor(s).LogoutContext(ctx)
+}
+
+// Register cria um novo usuário
+func Register(username, password string) error {
+	return DefaultClient().Register(username, password)
+}
+
+// RegisterContext é a variante de Register que respeita o cancelamento do contexto
+func RegisterContext(ctx context.Context, username, password string) error {
+	return DefaultClient().RegisterContext(ctx, username, password)
+}
+
+// CreateRoom cria uma nova sala enviando o nome desejado
+func CreateRoom(s data.Session, roomName string) (*data.Room, error) {
+	return clientFor(s).CreateRoom(roomName)
+}
+
+// CreateRoomContext é a variante de CreateRoom que respeita o cancelamento do contexto
+func CreateRoomContext(ctx context.Context, s data.Session, roomName string) (*data.Room, error) {
+	return clientFor(s).CreateRoomContext(ctx, roomName)
+}
+
+func JoinRoom(s data.Session, roomId string) error {
+	return clientFor(s).JoinRoom(roomId)
+}
+
+// JoinRoomContext é a variante de JoinRoom que respeita o cancelamento do contexto
+func JoinRoomContext(ctx context.Context, s data.Session, roomId string) error {
+	return clientFor(s).JoinRoomContext(ctx, roomId)
+}
+
+// GetUserRooms busca as salas que o usuário já entrou
+func GetUserRooms(s data.Session) ([]data.Room, error) {
+	return clientFor(s).GetUserRooms()
+}
+
+// GetUserRoomsContext é a variante de GetUserRooms que respeita o cancelamento do contexto
+func GetUserRoomsContext(ctx context.Context, s data.Session) ([]data.Room, error) {
+	return clientFor(s).GetUserRoomsContext(ctx)
+}
+
+func LoadChatMessages(s data.Session, roomId string) ([]data.Message, error) {
+	return clientFor(s).LoadChatMessages(roomId)
+}
+
+// LoadChatMessagesContext é a variante de LoadChatMessages que respeita o cancelamento do contexto
+func LoadChatMessagesContext(ctx context.Context, s data.Session, roomId string) ([]data.Message, error) {
+	return clientFor(s).LoadChatMessagesContext(ctx, roomId)
+}
+
+// LoadChatMessagesPage busca uma página do histórico de uma sala
+func LoadChatMessagesPage(s data.Session, roomId, before string, limit int) ([]data.Message, error) {
+	return clientFor(s).LoadChatMessagesPage(roomId, before, limit)
+}
+
+// LoadChatMessagesPageContext é a variante de LoadChatMessagesPage que respeita o cancelamento do contexto
+func LoadChatMessagesPageContext(ctx context.Context, s data.Session, roomId, before string, limit int) ([]data.Message, error) {
+	return clientFor(s).LoadChatMessagesPageContext(ctx, roomId, before, limit)
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mellojp/chatli/data"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// DefaultSendQueue é o tamanho padrão da fila de saída de uma Conn
+	DefaultSendQueue = 64
+	// writeWait é o prazo de cada escrita no socket
+	writeWait = 10 * time.Second
+)
+
+// ErrConnClosed é retornado por Send quando a conexão já foi encerrada
+var ErrConnClosed = errors.New("conexão encerrada")
+
+// ConnOptions configura uma Conn
+type ConnOptions struct {
+	// QueueSize limita quantas mensagens podem aguardar envio (0 usa DefaultSendQueue)
+	QueueSize int
+	// Heartbeat configura o ping/pong; Interval 0 desativa
+	Heartbeat Heartbeat
+}
+
+type outbound struct {
+	msg    any
+	result chan error
+}
+
+// Conn é dona de um *websocket.Conn e serializa o acesso a ele: uma goroutine de escrita
+// consome a fila de saída (mensagens e pings) e outra de leitura entrega os eventos
+// recebidos em Events.
+type Conn struct {
+	ws      *websocket.Conn
+	out     chan outbound
+	events  chan data.Event
+	latency chan time.Duration
+	done    chan struct{}
+
+	closeOnce sync.Once
+	mu        sync.Mutex
+	err       error
+}
+
+// NewConn encapsula a conexão e inicia as goroutines de leitura e escrita
+func NewConn(ws *websocket.Conn, opts ConnOptions) *Conn {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultSendQueue
+	}
+	c := &Conn{
+		ws:      ws,
+		out:     make(chan outbound, opts.QueueSize),
+		events:  make(chan data.Event),
+		latency: make(chan time.Duration, 1),
+		done:    make(chan struct{}),
+	}
+	if opts.Heartbeat.Interval > 0 {
+		installPongHandler(ws, opts.Heartbeat, func(rtt time.Duration) {
+			pushLatency(c.latency, rtt)
+		})
+	}
+	go c.readPump()
+	go c.writePump(opts.Heartbeat.Interval)
+	return c
+}
+
+// Send enfileira a mensagem e aguarda sua escrita no socket.
+// Bloqueia enquanto a fila estiver cheia, até o contexto expirar ou a conexão fechar.
+func (c *Conn) Send(ctx context.Context, msg any) error {
+	item := outbound{msg: msg, result: make(chan error, 1)}
+	select {
+	case c.out <- item:
+	case <-c.done:
+		return c.closedErr()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-item.result:
+		return err
+	case <-c.done:
+		return c.closedErr()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Events entrega os eventos recebidos, já decodificados; o canal é fechado quando a conexão termina.
+// Frames de tipo desconhecido chegam como data.UnknownEvent.
+func (c *Conn) Events() <-chan data.Event {
+	return c.events
+}
+
+// Latency entrega a latência medida a cada pong, mantendo apenas a mais recente
+func (c *Conn) Latency() <-chan time.Duration {
+	return c.latency
+}
+
+// Done é fechado quando a conexão termina
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err retorna o motivo do encerramento da conexão, se houver
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close envia um close frame ao servidor e encerra a conexão
+func (c *Conn) Close() error {
+	c.ws.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(writeWait))
+	c.shutdown(ErrConnClosed)
+	return nil
+}
+
+func (c *Conn) closedErr() error {
+	if err := c.Err(); err != nil {
+		return err
+	}
+	return ErrConnClosed
+}
+
+// shutdown registra o primeiro erro e libera as goroutines que aguardam a conexão
+func (c *Conn) shutdown(err error) {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		c.err = err
+		c.mu.Unlock()
+		close(c.done)
+		c.ws.Close()
+	})
+}
+
+func (c *Conn) readPump() {
+	defer close(c.events)
+	for {
+		_, raw, err := c.ws.ReadMessage()
+		if err != nil {
+			c.shutdown(err)
+			return
+		}
+		select {
+		case c.events <- data.DecodeEvent(raw):
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *Conn) writePump(pingInterval time.Duration) {
+	var ping <-chan time.Time
+	if pingInterval > 0 {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+
+	for {
+		select {
+		case item := <-c.out:
+			c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			err := c.ws.WriteJSON(item.msg)
+			item.result <- err
+			if err != nil {
+				c.shutdown(err)
+				return
+			}
+		case <-ping:
+			c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.ws.WriteMessage(websocket.PingMessage, pingPayload()); err != nil {
+				c.shutdown(err)
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mellojp/chatli/data"
+
+	"github.com/gorilla/websocket"
+)
+
+// echoConn abre uma Conn contra um servidor que devolve cada frame recebido
+func echoConn(t *testing.T) *Conn {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		for {
+			kind, raw, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := ws.WriteMessage(kind, raw); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewConn(ws, ConnOptions{QueueSize: 4})
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// TestConnConcurrentSend confere que envios simultâneos são serializados sem corromper frames
+func TestConnConcurrentSend(t *testing.T) {
+	c := echoConn(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	const n = 50
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			msg := data.Message{RoomId: "sala", Content: "oi", Nonce: string(rune('A' + i))}
+			if err := c.Send(ctx, msg); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	seen := make(map[string]bool)
+	for len(seen) < n {
+		select {
+		case ev := <-c.Events():
+			created, ok := ev.(data.MessageCreated)
+			if !ok {
+				t.Fatalf("evento %T: %+v", ev, ev)
+			}
+			seen[created.Nonce] = true
+		case <-ctx.Done():
+			t.Fatalf("recebidos %d de %d ecos", len(seen), n)
+		}
+	}
+	wg.Wait()
+}
+
+func TestConnClose(t *testing.T) {
+	c := echoConn(t)
+	c.Close()
+
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done não foi fechado")
+	}
+	if err := c.Send(context.Background(), data.Message{Content: "tarde"}); !errors.Is(err, ErrConnClosed) {
+		t.Errorf("Send após Close: erro %v, esperado ErrConnClosed", err)
+	}
+	if !errors.Is(c.Err(), ErrConnClosed) {
+		t.Errorf("Err = %v, esperado ErrConnClosed", c.Err())
+	}
+	// Events é fechado quando a leitura termina
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-c.Events():
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("Events não foi fechado")
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Erros sentinela para uso com errors.Is sobre um *Error
+var (
+	ErrUnauthorized = errors.New("não autorizado")
+	ErrForbidden    = errors.New("acesso negado")
+	ErrNotFound     = errors.New("não encontrado")
+	ErrConflict     = errors.New("conflito")
+)
+
+// maxErrorBody limita quanto do corpo de erro é lido do servidor
+const maxErrorBody = 4096
+
+// Error representa uma resposta de erro do servidor
+type Error struct {
+	// Op é a rota lógica da operação (ex.: "/auth/login"), sem o prefixo de caminho da URL base
+	// nem a query; é o campo a comparar para identificar a operação que falhou
+	Op         string
+	StatusCode int
+	// Endpoint é o caminho efetivamente requisitado, incluindo o prefixo da URL base
+	Endpoint string
+	// Message é a mensagem de erro enviada pelo servidor, quando houver
+	Message string
+
+	// desc descreve a operação para a mensagem de erro (ex.: "login falhou")
+	desc string
+}
+
+func (e *Error) Error() string {
+	desc := e.desc
+	if desc == "" {
+		desc = e.Op
+	}
+	if e.Message != "" {
+		return fmt.Sprintf("%s: status %d: %s", desc, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%s: status %d", desc, e.StatusCode)
+}
+
+// Is permite comparar o erro com os sentinelas a partir do status HTTP
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	}
+	return false
+}
+
+// RefreshUnsupported informa se o erro indica que o servidor não oferece renovação de token
+func RefreshUnsupported(err error) bool {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusMethodNotAllowed
+}
+
+// newError constrói um *Error da rota op a partir da resposta, extraindo a mensagem do corpo JSON ou texto
+func newError(desc, op string, resp *http.Response) error {
+	e := &Error{
+		Op:         op,
+		StatusCode: resp.StatusCode,
+		desc:       desc,
+	}
+	if resp.Request != nil && resp.Request.URL != nil {
+		e.Endpoint = resp.Request.URL.Path
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	var res struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &res) == nil {
+		if res.Error != "" {
+			e.Message = res.Error
+		} else {
+			e.Message = res.Message
+		}
+	} else if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		e.Message = strings.TrimSpace(string(body))
+	}
+	return e
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func response(status int, contentType, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {contentType}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    &http.Request{URL: &url.URL{Path: "/prefixo/rooms/join", RawQuery: "x=1"}},
+	}
+}
+
+func TestNewErrorMessage(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		want        string
+	}{
+		{"application/json", `{"error":"room not found"}`, "room not found"},
+		{"application/json", `{"message":"sala inexistente"}`, "sala inexistente"},
+		{"application/json", `{"error":"a","message":"b"}`, "a"},
+		{"text/plain", "  falhou feio\n", "falhou feio"},
+		{"text/html; charset=utf-8", "<html>erro</html>", ""},
+	}
+	for _, tt := range tests {
+		err := newError("entrada na sala falhou", "/rooms/join", response(http.StatusNotFound, tt.contentType, tt.body))
+		var apiErr *Error
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("newError retornou %T", err)
+		}
+		if apiErr.Message != tt.want {
+			t.Errorf("corpo %q: Message = %q, esperado %q", tt.body, apiErr.Message, tt.want)
+		}
+		if apiErr.Op != "/rooms/join" || apiErr.Endpoint != "/prefixo/rooms/join" {
+			t.Errorf("Op = %q, Endpoint = %q", apiErr.Op, apiErr.Endpoint)
+		}
+	}
+
+	err := newError("entrada na sala falhou", "/rooms/join", response(http.StatusNotFound, "application/json", `{"error":"room not found"}`))
+	if want := "entrada na sala falhou: status 404: room not found"; err.Error() != want {
+		t.Errorf("Error() = %q, esperado %q", err.Error(), want)
+	}
+}
+
+func TestErrorSentinels(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusForbidden, ErrForbidden},
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusConflict, ErrConflict},
+	}
+	sentinels := []error{ErrUnauthorized, ErrForbidden, ErrNotFound, ErrConflict}
+	for _, tt := range tests {
+		// Embrulhado, como faz o handshake do websocket
+		err := fmt.Errorf("dial: %w", &Error{Op: "/rooms", StatusCode: tt.status})
+		for _, sentinel := range sentinels {
+			if got := errors.Is(err, sentinel); got != (sentinel == tt.want) {
+				t.Errorf("status %d: errors.Is(%v) = %v", tt.status, sentinel, got)
+			}
+		}
+	}
+
+	for _, status := range []int{http.StatusNotFound, http.StatusMethodNotAllowed} {
+		if !RefreshUnsupported(&Error{StatusCode: status}) {
+			t.Errorf("RefreshUnsupported(%d) = false", status)
+		}
+	}
+	if RefreshUnsupported(&Error{StatusCode: http.StatusUnauthorized}) || RefreshUnsupported(errors.New("rede")) {
+		t.Error("RefreshUnsupported aceitou um erro que não indica falta de suporte")
+	}
+}
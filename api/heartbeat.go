@@ -0,0 +1,96 @@
+package api
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Heartbeat configura o ping/pong que detecta conexões meio abertas
+type Heartbeat struct {
+	// Interval é o intervalo entre pings
+	Interval time.Duration
+	// Timeout é quanto tempo a conexão pode ficar sem pong antes da leitura falhar
+	Timeout time.Duration
+}
+
+// DefaultHeartbeat envia um ping a cada 20s e derruba a conexão após 45s sem resposta
+func DefaultHeartbeat() Heartbeat {
+	return Heartbeat{
+		Interval: 20 * time.Second,
+		Timeout:  45 * time.Second,
+	}
+}
+
+// pingPayload carrega o instante de envio para que o pong permita medir o round-trip
+func pingPayload() []byte {
+	return []byte(strconv.FormatInt(time.Now().UnixNano(), 10))
+}
+
+// installPongHandler estende o deadline de leitura a cada pong e reporta a latência medida
+func installPongHandler(conn *websocket.Conn, hb Heartbeat, report func(time.Duration)) {
+	conn.SetReadDeadline(time.Now().Add(hb.Timeout))
+	conn.SetPongHandler(func(appData string) error {
+		conn.SetReadDeadline(time.Now().Add(hb.Timeout))
+
+		sent, err := strconv.ParseInt(appData, 10, 64)
+		if err != nil {
+			return nil
+		}
+		report(time.Since(time.Unix(0, sent)))
+		return nil
+	})
+}
+
+// pushLatency entrega a medição sem bloquear, mantendo apenas a mais recente
+func pushLatency(ch chan time.Duration, d time.Duration) {
+	select {
+	case <-ch:
+	default:
+	}
+	select {
+	case ch <- d:
+	default:
+	}
+}
+
+// StartHeartbeat instala o pong handler e o deadline de leitura numa conexão crua e envia
+// pings periodicamente. O canal retornado recebe a latência (round-trip) medida a cada pong
+// e é fechado quando a conexão deixa de aceitar pings.
+//
+// Deve ser chamado antes da primeira leitura da conexão. Conexões encapsuladas em Conn já
+// fazem o heartbeat na própria goroutine de escrita.
+func StartHeartbeat(conn *websocket.Conn, hb Heartbeat) <-chan time.Duration {
+	latency := make(chan time.Duration, 1)
+	var mu sync.Mutex
+	closed := false
+
+	installPongHandler(conn, hb, func(rtt time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !closed {
+			pushLatency(latency, rtt)
+		}
+	})
+
+	go func() {
+		ticker := time.NewTicker(hb.Interval)
+		defer ticker.Stop()
+		defer func() {
+			mu.Lock()
+			closed = true
+			close(latency)
+			mu.Unlock()
+		}()
+
+		for range ticker.C {
+			// WriteControl pode ser chamado em paralelo com os demais métodos de escrita
+			if err := conn.WriteControl(websocket.PingMessage, pingPayload(), time.Now().Add(hb.Interval)); err != nil {
+				return
+			}
+		}
+	}()
+	return latency
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrNoExpiry indica que o token não tem a claim exp
+var ErrNoExpiry = errors.New("token sem expiração")
+
+// TokenExpiry lê a claim exp do JWT sem verificar a assinatura.
+// Serve apenas para o cliente se antecipar à expiração; quem valida o token é o servidor.
+func TokenExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, errors.New("token JWT malformado")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var claims struct {
+		Exp json.Number `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, err
+	}
+	if claims.Exp == "" {
+		return time.Time{}, ErrNoExpiry
+	}
+	exp, err := claims.Exp.Float64()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(exp), 0), nil
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func jwtWith(payload string) string {
+	enc := base64.RawURLEncoding.EncodeToString
+	return enc([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + enc([]byte(payload)) + ".assinatura"
+}
+
+func TestTokenExpiry(t *testing.T) {
+	got, err := TokenExpiry(jwtWith(`{"sub":"1","exp":1700000000}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Unix(1700000000, 0); !got.Equal(want) {
+		t.Errorf("TokenExpiry = %v, esperado %v", got, want)
+	}
+
+	// Alguns servidores mandam exp com casas decimais
+	got, err = TokenExpiry(jwtWith(`{"exp":1700000000.5}`))
+	if err != nil || got.Unix() != 1700000000 {
+		t.Errorf("exp decimal: %v, %v", got, err)
+	}
+}
+
+func TestTokenExpiryErrors(t *testing.T) {
+	if _, err := TokenExpiry(jwtWith(`{"sub":"1"}`)); !errors.Is(err, ErrNoExpiry) {
+		t.Errorf("sem exp: erro %v, esperado ErrNoExpiry", err)
+	}
+	for _, token := range []string{"", "abc", "a.b", "a.!!!.c", jwtWith(`não é json`)} {
+		if _, err := TokenExpiry(token); err == nil {
+			t.Errorf("TokenExpiry(%q) não falhou", token)
+		}
+	}
+}
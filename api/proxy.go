@@ -0,0 +1,123 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// errHTTPSProxy é retornado para proxies https://: o dialer do websocket só fala com o proxy
+// em texto puro (http ou socks5), e a API e o websocket precisam seguir a mesma rota
+var errHTTPSProxy = errors.New("proxy https:// não suportado pelo websocket: use http://, socks5:// ou socks5h://")
+
+// ProxyFunc monta a função de proxy para a URL informada (http, socks5 ou socks5h),
+// ignorando os destinos listados em noProxy (mesmo formato de NO_PROXY; vazio usa NO_PROXY).
+// Uma URL vazia usa HTTPS_PROXY/HTTP_PROXY/NO_PROXY do ambiente.
+func ProxyFunc(rawURL, noProxy string) (func(*http.Request) (*url.URL, error), error) {
+	if rawURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	proxyURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("proxy inválido: %w", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "socks5", "socks5h":
+	case "https":
+		return nil, errHTTPSProxy
+	default:
+		return nil, fmt.Errorf("proxy inválido: esquema %q não suportado", proxyURL.Scheme)
+	}
+	if noProxy == "" {
+		noProxy = os.Getenv("NO_PROXY")
+		if noProxy == "" {
+			noProxy = os.Getenv("no_proxy")
+		}
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(req.URL, noProxy) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}, nil
+}
+
+// SetProxy faz a API e o websocket passarem pelo mesmo proxy
+func (c *Client) SetProxy(rawURL, noProxy string) error {
+	proxy, err := ProxyFunc(rawURL, noProxy)
+	if err != nil {
+		return err
+	}
+	c.configureTransport(func(t *http.Transport, d *websocket.Dialer) {
+		t.Proxy = proxy
+		d.Proxy = dialerProxy(proxy)
+	})
+	return nil
+}
+
+// dialerProxy adapta a função de proxy ao dialer do gorilla, que só conhece os esquemas http
+// e socks5 (no socks5h a resolução de nomes já é feita pelo proxy). Um proxy https:// vindo
+// do ambiente resulta num erro claro em vez do "unknown scheme" do gorilla.
+func dialerProxy(proxy func(*http.Request) (*url.URL, error)) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		u, err := proxy(req)
+		if u != nil && err == nil && u.Scheme == "https" {
+			return nil, errHTTPSProxy
+		}
+		if u == nil || err != nil || u.Scheme != "socks5h" {
+			return u, err
+		}
+		copied := *u
+		copied.Scheme = "socks5"
+		return &copied, nil
+	}
+}
+
+// bypassProxy informa se o destino está na lista noProxy
+func bypassProxy(target *url.URL, noProxy string) bool {
+	host := target.Hostname()
+	port := target.Port()
+	ip := net.ParseIP(host)
+
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		entryHost, entryPort := entry, ""
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			entryHost, entryPort = h, p
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+		entryHost = strings.TrimPrefix(entryHost, "*")
+		if strings.HasPrefix(entryHost, ".") {
+			if strings.HasSuffix(host, entryHost) || host == entryHost[1:] {
+				return true
+			}
+			continue
+		}
+		if host == entryHost || strings.HasSuffix(host, "."+entryHost) {
+			return true
+		}
+	}
+	return false
+}
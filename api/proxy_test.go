@@ -0,0 +1,52 @@
+package api
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestBypassProxy(t *testing.T) {
+	tests := []struct {
+		target  string
+		noProxy string
+		want    bool
+	}{
+		{"http://localhost:8080", "localhost", true},
+		{"http://api.example.com", "example.com", true},
+		{"http://api.example.com", ".example.com", true},
+		{"http://example.com", ".example.com", true},
+		{"http://api.example.com", "*.example.com", true},
+		{"http://badexample.com", "example.com", false},
+		{"http://example.com:8080", "example.com:8080", true},
+		{"http://example.com:9090", "example.com:8080", false},
+		{"http://10.1.2.3", "10.0.0.0/8", true},
+		{"http://192.168.0.1", "10.0.0.0/8", false},
+		{"http://example.org", " foo.com , EXAMPLE.ORG ", true},
+		{"http://qualquer.coisa", "*", true},
+		{"http://example.com", "", false},
+	}
+	for _, tt := range tests {
+		target, err := url.Parse(tt.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := bypassProxy(target, tt.noProxy); got != tt.want {
+			t.Errorf("bypassProxy(%s, %q) = %v, esperado %v", tt.target, tt.noProxy, got, tt.want)
+		}
+	}
+}
+
+func TestProxyFuncSchemes(t *testing.T) {
+	for _, raw := range []string{"http://proxy:3128", "socks5://proxy:1080", "socks5h://proxy:1080"} {
+		if _, err := ProxyFunc(raw, ""); err != nil {
+			t.Errorf("ProxyFunc(%q): %v", raw, err)
+		}
+	}
+	if _, err := ProxyFunc("https://proxy:443", ""); !errors.Is(err, errHTTPSProxy) {
+		t.Errorf("proxy https: erro %v, esperado errHTTPSProxy", err)
+	}
+	if _, err := ProxyFunc("ftp://proxy", ""); err == nil {
+		t.Error("esquema ftp não foi rejeitado")
+	}
+}
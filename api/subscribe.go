@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/mellojp/chatli/data"
+)
+
+// Subscribe passa a receber os eventos das salas informadas. Depois da primeira inscrição,
+// o servidor deixa de enviar os eventos das demais salas e manda apenas data.RoomUnread.
+// Sem salas, apenas ativa esse modo. Servidores sem suporte ignoram o frame e continuam
+// enviando tudo.
+func (c *Conn) Subscribe(ctx context.Context, roomIds ...string) error {
+	return c.sendEvent(ctx, data.Subscribe{RoomIds: nonNil(roomIds)})
+}
+
+// SubscribeRead é a variante de Subscribe que informa até onde o usuário leu cada sala, para
+// que o servidor conte as não lidas da primeira inscrição a partir desses horários
+func (c *Conn) SubscribeRead(ctx context.Context, read map[string]time.Time, roomIds ...string) error {
+	return c.sendEvent(ctx, data.Subscribe{RoomIds: nonNil(roomIds), Read: read})
+}
+
+// Unsubscribe deixa de receber os eventos das salas informadas
+func (c *Conn) Unsubscribe(ctx context.Context, roomIds ...string) error {
+	return c.sendEvent(ctx, data.Unsubscribe{RoomIds: nonNil(roomIds)})
+}
+
+func (c *Conn) sendEvent(ctx context.Context, ev data.Event) error {
+	env, err := data.NewEnvelope(ev)
+	if err != nil {
+		return err
+	}
+	return c.Send(ctx, env)
+}
+
+// nonNil garante que a lista seja codificada como [] e não null
+func nonNil(ids []string) []string {
+	if ids == nil {
+		return []string{}
+	}
+	return ids
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gorilla/websocket"
+)
+
+// TLSOptions configura a verificação do servidor e o certificado de cliente
+type TLSOptions struct {
+	// CAFile é um bundle PEM de CAs confiáveis somadas às do sistema
+	CAFile string
+	// CertFile e KeyFile são o certificado de cliente em PEM (mTLS)
+	CertFile string
+	KeyFile  string
+	// InsecureSkipVerify desativa a verificação do certificado; apenas para desenvolvimento local
+	InsecureSkipVerify bool
+}
+
+// IsZero informa se nenhuma opção foi definida
+func (o TLSOptions) IsZero() bool {
+	return o == TLSOptions{}
+}
+
+// TLSFromEnv lê as opções de TLS de TLS_CA_FILE, TLS_CERT_FILE, TLS_KEY_FILE e TLS_INSECURE_SKIP_VERIFY
+func TLSFromEnv() TLSOptions {
+	insecure := os.Getenv("TLS_INSECURE_SKIP_VERIFY")
+	return TLSOptions{
+		CAFile:             os.Getenv("TLS_CA_FILE"),
+		CertFile:           os.Getenv("TLS_CERT_FILE"),
+		KeyFile:            os.Getenv("TLS_KEY_FILE"),
+		InsecureSkipVerify: insecure == "1" || insecure == "true",
+	}
+}
+
+// Config monta o *tls.Config correspondente às opções
+func (o TLSOptions) Config() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler CA: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("nenhum certificado válido em %s", o.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao carregar certificado de cliente: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// SetTLS aplica as opções de TLS tanto ao cliente HTTP quanto ao dialer do websocket
+func (c *Client) SetTLS(o TLSOptions) error {
+	cfg, err := o.Config()
+	if err != nil {
+		return err
+	}
+
+	c.configureTransport(func(t *http.Transport, d *websocket.Dialer) {
+		t.TLSClientConfig = cfg
+		d.TLSClientConfig = cfg
+	})
+	return nil
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+func (c *Client) dialer() *websocket.Dialer {
+	if c.Dialer != nil {
+		return c.Dialer
+	}
+	return websocket.DefaultDialer
+}
+
+// configureTransport aplica a mesma alteração ao transporte HTTP e ao dialer do websocket,
+// para que REST e socket sigam sempre a mesma rota (proxy) e as mesmas regras de TLS.
+// Os dois são copiados antes da alteração, sem afetar os padrões globais.
+func (c *Client) configureTransport(apply func(t *http.Transport, d *websocket.Dialer)) {
+	var transport *http.Transport
+	if t, ok := c.httpClient().Transport.(*http.Transport); ok {
+		transport = t.Clone()
+	} else {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	dialer := *c.dialer()
+
+	apply(transport, &dialer)
+
+	httpClient := *c.httpClient()
+	httpClient.Transport = transport
+	c.HTTPClient = &httpClient
+	c.Dialer = &dialer
+}
@@ -1,8 +1,12 @@
 package api
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
 
 	"github.com/mellojp/chatli/data"
 
@@ -11,26 +15,82 @@ import (
 
 var WS_URL string
 
-func getWSURL() string {
+func getWSURL(apiURL string) string {
 	if WS_URL != "" {
 		return WS_URL
 	}
 	val := os.Getenv("WS_URL")
 	if val == "" {
-		return "ws://localhost:8080"
+		return DeriveWSURL(apiURL)
 	}
 	return val
 }
 
-func ConnectWebSocket(s data.Session) (*websocket.Conn, error) {
-	connUrl := getWSURL() + "/ws"
-	
+// DeriveWSURL converte a URL da API na URL do websocket do mesmo servidor,
+// trocando http por ws e https por wss
+func DeriveWSURL(apiURL string) string {
+	u, err := url.Parse(strings.TrimRight(apiURL, "/"))
+	if err != nil || u.Host == "" {
+		return "ws://localhost:8080"
+	}
+	switch u.Scheme {
+	case "https", "wss":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+	return u.String()
+}
+
+// ConnectWebSocketContext abre a conexão global de websocket autenticada com a sessão do Client.
+// O contexto limita apenas o handshake; a conexão aberta não é afetada pelo cancelamento.
+func (c *Client) ConnectWebSocketContext(ctx context.Context) (*websocket.Conn, error) {
+	ctx, cancel := withDeadline(ctx, c.DialTimeout)
+	defer cancel()
+
+	connUrl := c.WSURL + "/ws"
+
 	headers := http.Header{}
-	headers.Add("Authorization", "Bearer "+s.Token)
+	headers.Add("Authorization", "Bearer "+c.Session.Token)
+	if c.UserAgent != "" {
+		headers.Add("User-Agent", c.UserAgent)
+	}
 
-	socket, _, err := websocket.DefaultDialer.Dial(connUrl, headers)
+	socket, resp, err := c.dialer().DialContext(ctx, connUrl, headers)
 	if err != nil {
+		// Um handshake recusado também é um *Error, para que errors.Is(err, ErrUnauthorized) funcione
+		if resp != nil && resp.StatusCode >= 400 {
+			return nil, fmt.Errorf("%w: %w", err, newError("conexão do websocket falhou", "/ws", resp))
+		}
 		return nil, err
 	}
 	return socket, nil
 }
+
+// ConnectWebSocket abre a conexão global de websocket autenticada com a sessão do Client
+func (c *Client) ConnectWebSocket() (*websocket.Conn, error) {
+	return c.ConnectWebSocketContext(context.Background())
+}
+
+// Connect abre o websocket e o encapsula numa Conn com heartbeat e fila de envio
+func (c *Client) Connect(ctx context.Context) (*Conn, error) {
+	ws, err := c.ConnectWebSocketContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(ws, ConnOptions{QueueSize: c.SendQueue, Heartbeat: c.Heartbeat}), nil
+}
+
+func ConnectWebSocket(s data.Session) (*websocket.Conn, error) {
+	return clientFor(s).ConnectWebSocket()
+}
+
+// ConnectWebSocketContext é a variante de ConnectWebSocket que respeita o cancelamento do contexto
+func ConnectWebSocketContext(ctx context.Context, s data.Session) (*websocket.Conn, error) {
+	return clientFor(s).ConnectWebSocketContext(ctx)
+}
+
+// Connect abre o websocket da sessão encapsulado numa Conn com as opções padrão
+func Connect(ctx context.Context, s data.Session) (*Conn, error) {
+	return clientFor(s).Connect(ctx)
+}
@@ -0,0 +1,24 @@
+package api
+
+import "testing"
+
+func TestDeriveWSURL(t *testing.T) {
+	tests := []struct {
+		apiURL string
+		want   string
+	}{
+		{"http://localhost:8080", "ws://localhost:8080"},
+		{"https://chat.example.com", "wss://chat.example.com"},
+		{"https://chat.example.com/api/", "wss://chat.example.com/api"},
+		{"http://10.0.0.1:9000/prefixo", "ws://10.0.0.1:9000/prefixo"},
+		{"wss://chat.example.com", "wss://chat.example.com"},
+		{"ws://chat.example.com", "ws://chat.example.com"},
+		{"", "ws://localhost:8080"},
+		{"não é url", "ws://localhost:8080"},
+	}
+	for _, tt := range tests {
+		if got := DeriveWSURL(tt.apiURL); got != tt.want {
+			t.Errorf("DeriveWSURL(%q) = %q, esperado %q", tt.apiURL, got, tt.want)
+		}
+	}
+}
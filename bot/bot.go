@@ -0,0 +1,394 @@
+// Package bot é um framework para bots sem interface: faz login, escuta o websocket,
+// despacha as mensagens para handlers e comandos (!cmd) e responde respeitando um
+// limite de envios, reconectando sozinho quando a conexão cai.
+//
+//	b := bot.New(api.DefaultClient())
+//	b.Command("ping", func(ctx context.Context, msg data.Message, args []string) error {
+//		return b.Reply(ctx, msg, "pong")
+//	})
+//	if err := b.Login(ctx, "bot", "secret"); err != nil { ... }
+//	err := b.Run(ctx)
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mellojp/chatli/api"
+	"github.com/mellojp/chatli/data"
+
+	"github.com/gorilla/websocket"
+)
+
+// DefaultPrefix é o prefixo padrão dos comandos
+const DefaultPrefix = "!"
+
+// DefaultQueueSize é o tamanho padrão da fila de eventos aguardando os handlers
+const DefaultQueueSize = 256
+
+// ErrNotConnected é retornado por Send enquanto o bot não tem conexão aberta
+var ErrNotConnected = errors.New("bot não está conectado")
+
+// HandlerFunc trata uma mensagem recebida
+type HandlerFunc func(ctx context.Context, msg data.Message) error
+
+// EventFunc trata um evento do websocket que não é mensagem nova (edição, entrada na sala, etc.)
+type EventFunc func(ctx context.Context, ev data.Event) error
+
+// CommandFunc trata um comando; args são as palavras após o nome do comando
+type CommandFunc func(ctx context.Context, msg data.Message, args []string) error
+
+// Filter decide se uma mensagem deve chegar a um handler
+type Filter func(msg data.Message) bool
+
+// InRooms aceita apenas mensagens das salas informadas
+func InRooms(ids ...string) Filter {
+	rooms := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		rooms[id] = true
+	}
+	return func(msg data.Message) bool {
+		return rooms[msg.RoomId]
+	}
+}
+
+type handler struct {
+	filter Filter
+	fn     HandlerFunc
+}
+
+type command struct {
+	filter Filter
+	fn     CommandFunc
+}
+
+// Bot mantém a sessão, a conexão e os handlers registrados.
+// Handlers e comandos rodam fora do laço de leitura do websocket, numa fila atendida por
+// Workers goroutines; com um único worker (o padrão) rodam na ordem de chegada.
+type Bot struct {
+	Client *api.Client
+	// Prefix é o prefixo dos comandos (padrão DefaultPrefix)
+	Prefix string
+	// Rooms restringe o bot às salas informadas; vazio aceita todas
+	Rooms []string
+	// Limiter limita os envios do bot; nil desativa o limite
+	Limiter *Limiter
+	// IgnoreSelf descarta as mensagens enviadas pelo próprio bot (padrão true)
+	IgnoreSelf bool
+	// Logger recebe os erros dos handlers e as quedas de conexão
+	Logger *log.Logger
+	// Workers é quantos eventos são tratados ao mesmo tempo (padrão 1)
+	Workers int
+	// QueueSize limita os eventos à espera de um worker (padrão DefaultQueueSize). Com a
+	// fila cheia, eventos novos são descartados para que o websocket continue sendo lido.
+	QueueSize int
+
+	handlers      []handler
+	commands      map[string]command
+	eventHandlers []EventFunc
+
+	// mu protege a conexão e a sessão, trocadas pelo laço de Run enquanto os handlers enviam
+	mu       sync.Mutex
+	conn     *api.Conn
+	session  data.Session
+	username string
+	password string
+}
+
+// New cria um bot que usa o Client informado, com limite padrão de 1 envio por segundo
+// e rajadas de até 5 mensagens
+func New(c *api.Client) *Bot {
+	return &Bot{
+		Client:     c,
+		Prefix:     DefaultPrefix,
+		Limiter:    NewLimiter(time.Second, 5),
+		IgnoreSelf: true,
+		Logger:     log.Default(),
+		Workers:    1,
+		QueueSize:  DefaultQueueSize,
+		commands:   make(map[string]command),
+	}
+}
+
+// Login autentica o bot; as credenciais são guardadas para renovar a sessão ao reconectar.
+// O Client não é alterado: a sessão fica com o bot (veja Session).
+func (b *Bot) Login(ctx context.Context, username, password string) error {
+	c := *b.Client
+	session, err := c.LoginContext(ctx, username, password)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	b.session = *session
+	b.username, b.password = username, password
+	b.mu.Unlock()
+	return nil
+}
+
+// Session retorna a sessão atual do bot: a do último Login ou, sem ele, a do Client
+func (b *Bot) Session() data.Session {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.session.Token == "" {
+		return b.Client.Session
+	}
+	return b.session
+}
+
+// OnMessage registra um handler para todas as mensagens
+func (b *Bot) OnMessage(fn HandlerFunc) {
+	b.Handle(nil, fn)
+}
+
+// Handle registra um handler para as mensagens aceitas pelo filtro (nil aceita todas)
+func (b *Bot) Handle(filter Filter, fn HandlerFunc) {
+	b.handlers = append(b.handlers, handler{filter: filter, fn: fn})
+}
+
+// OnEvent registra um handler para os demais eventos do websocket (data.MessageEdited,
+// data.MemberJoined, ...); mensagens novas vão para OnMessage e Command
+func (b *Bot) OnEvent(fn EventFunc) {
+	b.eventHandlers = append(b.eventHandlers, fn)
+}
+
+// Command registra um comando, acionado por mensagens como "!nome arg1 arg2"
+func (b *Bot) Command(name string, fn CommandFunc) {
+	b.HandleCommand(nil, name, fn)
+}
+
+// HandleCommand registra um comando aceito apenas nas mensagens que passam pelo filtro
+func (b *Bot) HandleCommand(filter Filter, name string, fn CommandFunc) {
+	if b.commands == nil {
+		b.commands = make(map[string]command)
+	}
+	b.commands[strings.ToLower(name)] = command{filter: filter, fn: fn}
+}
+
+// Send envia uma mensagem de chat para a sala, respeitando o Limiter
+func (b *Bot) Send(ctx context.Context, roomId, content string) error {
+	b.mu.Lock()
+	conn := b.conn
+	b.mu.Unlock()
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	if err := b.Limiter.Wait(ctx); err != nil {
+		return err
+	}
+	session := b.Session()
+	return conn.Send(ctx, data.Message{
+		Type:           "chat",
+		UserId:         session.UserId,
+		SenderUsername: session.Username,
+		Content:        content,
+		RoomId:         roomId,
+		SentAt:         time.Now(),
+		Nonce:          data.NewNonce(),
+	})
+}
+
+// Reply responde na sala de origem da mensagem
+func (b *Bot) Reply(ctx context.Context, msg data.Message, content string) error {
+	return b.Send(ctx, msg.RoomId, content)
+}
+
+// Run conecta o bot e despacha as mensagens até o contexto ser cancelado,
+// reconectando com backoff quando a conexão cai
+func (b *Bot) Run(ctx context.Context) error {
+	if b.Session().Token == "" {
+		return errors.New("bot sem sessão: chame Login antes de Run")
+	}
+
+	// A fila e os workers sobrevivem às reconexões
+	queue := make(chan data.Event, max(b.QueueSize, 1))
+	var wg sync.WaitGroup
+	for range max(b.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ev := range queue {
+				b.dispatchEvent(ctx, ev)
+			}
+		}()
+	}
+	defer func() {
+		close(queue)
+		wg.Wait()
+	}()
+
+	backoff := api.NewBackoff()
+	for {
+		conn, err := b.connect(ctx)
+		if err == nil {
+			backoff.Reset()
+			err = b.serve(ctx, conn, queue)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		wait := backoff.Next()
+		b.logf("conexão perdida (%v), reconectando em %s", err, wait.Round(time.Millisecond))
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// connect abre o websocket com a sessão atual; se o servidor recusar o handshake, refaz o
+// login antes de desistir
+func (b *Bot) connect(ctx context.Context) (*api.Conn, error) {
+	conn, err := b.client().Connect(ctx)
+	b.mu.Lock()
+	username, password := b.username, b.password
+	b.mu.Unlock()
+	if errors.Is(err, websocket.ErrBadHandshake) && username != "" {
+		if err := b.Login(ctx, username, password); err != nil {
+			return nil, fmt.Errorf("renovação da sessão falhou: %w", err)
+		}
+		conn, err = b.client().Connect(ctx)
+	}
+	return conn, err
+}
+
+// client retorna uma cópia do Client com a sessão atual do bot
+func (b *Bot) client() *api.Client {
+	c := *b.Client
+	c.Session = b.Session()
+	return &c
+}
+
+// serve lê os eventos da conexão até ela cair, passando-os para a fila dos workers. A leitura
+// nunca espera os handlers: parada, ela deixaria de processar os pongs e a conexão cairia.
+func (b *Bot) serve(ctx context.Context, conn *api.Conn, queue chan<- data.Event) error {
+	b.mu.Lock()
+	b.conn = conn
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.conn = nil
+		b.mu.Unlock()
+		conn.Close()
+	}()
+
+	// Restrito a algumas salas, o bot não precisa dos eventos das demais
+	if len(b.Rooms) > 0 {
+		if err := conn.Subscribe(ctx, b.Rooms...); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case ev, ok := <-conn.Events():
+			if !ok {
+				return conn.Err()
+			}
+			select {
+			case queue <- ev:
+			default:
+				b.logf("fila de eventos cheia, %s descartado", ev.EventType())
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// dispatchEvent entrega mensagens novas a dispatch e os demais eventos aos handlers de OnEvent
+func (b *Bot) dispatchEvent(ctx context.Context, ev data.Event) {
+	switch ev := ev.(type) {
+	case data.MessageCreated:
+		b.dispatch(ctx, ev.Message)
+		return
+	case data.UnknownEvent:
+		b.logf("evento desconhecido %q ignorado: %s (%v)", ev.Type, ev.Raw, ev.Err)
+		return
+	}
+	for _, fn := range b.eventHandlers {
+		if err := fn(ctx, ev); err != nil {
+			b.logf("handler de %s: %v", ev.EventType(), err)
+		}
+	}
+}
+
+// dispatch entrega a mensagem aos handlers e ao comando correspondente
+func (b *Bot) dispatch(ctx context.Context, msg data.Message) {
+	if !b.accepts(msg) {
+		return
+	}
+
+	for _, h := range b.handlers {
+		if h.filter != nil && !h.filter(msg) {
+			continue
+		}
+		if err := h.fn(ctx, msg); err != nil {
+			b.logf("handler: %v", err)
+		}
+	}
+
+	name, args, ok := b.parseCommand(msg.Content)
+	if !ok {
+		return
+	}
+	cmd, ok := b.commands[name]
+	if !ok || (cmd.filter != nil && !cmd.filter(msg)) {
+		return
+	}
+	if err := cmd.fn(ctx, msg, args); err != nil {
+		b.logf("comando %s%s: %v", b.prefix(), name, err)
+	}
+}
+
+// accepts aplica os filtros globais do bot
+func (b *Bot) accepts(msg data.Message) bool {
+	if msg.Type != "" && msg.Type != "chat" {
+		return false
+	}
+	if b.IgnoreSelf && msg.UserId != "" && msg.UserId == b.Session().UserId {
+		return false
+	}
+	if len(b.Rooms) == 0 {
+		return true
+	}
+	for _, id := range b.Rooms {
+		if id == msg.RoomId {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCommand separa "!nome arg1 arg2" em nome e argumentos
+func (b *Bot) parseCommand(content string) (string, []string, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(content), b.prefix())
+	if !ok {
+		return "", nil, false
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	return strings.ToLower(fields[0]), fields[1:], true
+}
+
+func (b *Bot) prefix() string {
+	if b.Prefix == "" {
+		return DefaultPrefix
+	}
+	return b.Prefix
+}
+
+func (b *Bot) logf(format string, args ...any) {
+	if b.Logger != nil {
+		b.Logger.Printf("bot: "+format, args...)
+	}
+}
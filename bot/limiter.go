@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter é um balde de fichas simples: permite rajadas de até Burst envios e
+// depois um envio a cada Interval
+type Limiter struct {
+	Interval time.Duration
+	Burst    int
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter cria um Limiter com o intervalo e a rajada informados
+func NewLimiter(interval time.Duration, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{Interval: interval, Burst: burst, tokens: float64(burst)}
+}
+
+// Wait bloqueia até haver uma ficha disponível ou o contexto ser cancelado
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil || l.Interval <= 0 {
+		return ctx.Err()
+	}
+	for {
+		wait := l.reserve()
+		if wait == 0 {
+			return nil
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// reserve consome uma ficha se houver; caso contrário retorna quanto falta para a próxima
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens += float64(now.Sub(l.last)) / float64(l.Interval)
+		if l.tokens > float64(l.Burst) {
+			l.tokens = float64(l.Burst)
+		}
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) * float64(l.Interval))
+}
@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiterBurst(t *testing.T) {
+	l := NewLimiter(time.Hour, 3)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if wait := l.reserve(); wait != 0 {
+			t.Fatalf("envio %d da rajada esperou %v", i, wait)
+		}
+	}
+	if wait := l.reserve(); wait <= 0 || wait > time.Hour {
+		t.Errorf("após a rajada: espera %v, esperado até 1h", wait)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait sem fichas: erro %v, esperado DeadlineExceeded", err)
+	}
+}
+
+func TestLimiterRefill(t *testing.T) {
+	l := NewLimiter(20*time.Millisecond, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A primeira ficha é imediata; as outras duas chegam a cada Interval
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("três envios em %v, esperado ao menos ~40ms", elapsed)
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	var l *Limiter
+	if err := l.Wait(context.Background()); err != nil {
+		t.Errorf("Limiter nil: %v", err)
+	}
+	if err := NewLimiter(0, 1).Wait(context.Background()); err != nil {
+		t.Errorf("Interval zero: %v", err)
+	}
+}
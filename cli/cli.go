@@ -0,0 +1,270 @@
+// Package cli implementa a linha de comando do chatli: a TUI e os subcomandos para scripts
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/mellojp/chatli/api"
+	"github.com/mellojp/chatli/config"
+	"github.com/mellojp/chatli/data"
+	"github.com/mellojp/chatli/store"
+	"github.com/mellojp/chatli/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Version é a versão do chatli, definida no build com -ldflags "-X github.com/mellojp/chatli/cli.Version=..."
+var Version = "dev"
+
+// errUsage indica erro de uso da linha de comando (código de saída 2)
+var errUsage = errors.New("uso incorreto")
+
+// errNotLoggedIn é retornado pelos comandos que precisam de uma sessão salva
+var errNotLoggedIn = errors.New("nenhuma sessão salva: execute 'chatli login' primeiro")
+
+const usage = `uso: chatli [flags] [comando] [argumentos]
+
+Sem comando, abre a interface interativa.
+
+Comandos:
+  login                   autentica e salva a sessão do perfil
+  rooms list              lista as salas do usuário
+  rooms create NOME       cria uma sala
+  rooms join ID           entra numa sala
+  history SALA            mostra o histórico de uma sala
+  send SALA [MENSAGEM]    envia uma mensagem ou, sem ela, cada linha do stdin (--room, --multiline)
+  tail --room ID          acompanha as salas, uma mensagem por linha (--format text|json)
+  version                 mostra a versão
+
+Flags globais (aceitas antes ou depois do comando):
+`
+
+// globals são as flags aceitas por todos os comandos
+type globals struct {
+	apiURL  string
+	wsURL   string
+	profile string
+	json    bool
+}
+
+// register adiciona as flags globais ao FlagSet. Os valores já lidos antes do comando
+// viram o padrão, para que não sejam apagados ao interpretar as flags do subcomando.
+func (g *globals) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.apiURL, "api-url", g.apiURL, "URL da API (sobrepõe o perfil e API_URL)")
+	fs.StringVar(&g.wsURL, "ws-url", g.wsURL, "URL do websocket (padrão: derivada da URL da API)")
+	fs.StringVar(&g.profile, "profile", g.profile, "perfil de servidor definido no arquivo de configuração")
+	fs.BoolVar(&g.json, "json", g.json, "saída em JSON")
+}
+
+// env é o contexto de execução de um comando
+type env struct {
+	globals
+	cfg    *config.Config
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// Run executa a linha de comando e retorna o código de saída
+func Run(args []string) int {
+	e := &env{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+
+	fs := e.flagSet("chatli")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(e.stderr, "AVISO: Falha ao carregar configuração: %v\n", err)
+	}
+	e.cfg = cfg
+
+	err = e.dispatch(fs.Args())
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		if err != errUsage {
+			fmt.Fprintf(e.stderr, "chatli: %v\n", err)
+		}
+		fmt.Fprint(e.stderr, usage)
+		fs.PrintDefaults()
+		return 2
+	}
+	fmt.Fprintf(e.stderr, "chatli: %v\n", err)
+	return 1
+}
+
+// flagSet cria um FlagSet com as flags globais já registradas
+func (e *env) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprint(e.stderr, usage)
+		fs.PrintDefaults()
+	}
+	e.globals.register(fs)
+	return fs
+}
+
+func (e *env) dispatch(args []string) error {
+	if len(args) == 0 {
+		return e.runTUI()
+	}
+	cmd, rest := args[0], args[1:]
+	switch cmd {
+	case "login":
+		return e.runLogin(rest)
+	case "rooms":
+		if len(rest) == 0 {
+			return errUsage
+		}
+		switch rest[0] {
+		case "list", "ls":
+			return e.runRoomsList(rest[1:])
+		case "create":
+			return e.runRoomsCreate(rest[1:])
+		case "join":
+			return e.runRoomsJoin(rest[1:])
+		}
+		return errUsage
+	case "history":
+		return e.runHistory(rest)
+	case "send":
+		return e.runSend(rest)
+	case "tail":
+		return e.runTail(rest)
+	case "version":
+		return e.runVersion(rest)
+	case "help":
+		return errUsage
+	}
+	fmt.Fprintf(e.stderr, "chatli: comando desconhecido: %s\n", cmd)
+	return errUsage
+}
+
+// parse interpreta as flags do subcomando (incluindo as globais) e valida a quantidade de argumentos
+func (e *env) parse(fs *flag.FlagSet, args []string, nargs int) ([]string, error) {
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != nargs {
+		return nil, errUsage
+	}
+	return rest, nil
+}
+
+// parseArgs interpreta as flags mesmo quando aparecem depois dos argumentos posicionais
+// (ex.: "history SALA --limit 10"); "--" encerra as flags
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// resolveProfile resolve o perfil em uso, aplicando --api-url e --ws-url por cima dele
+func (e *env) resolveProfile() (config.Profile, error) {
+	p, err := e.cfg.Resolve(e.globals.profile)
+	if err != nil {
+		return p, err
+	}
+	if e.apiURL != "" {
+		p.APIURL = e.apiURL
+		// A URL do websocket do perfil pertence ao servidor antigo
+		p.WSURL = ""
+	}
+	if e.wsURL != "" {
+		p.WSURL = e.wsURL
+	}
+	return p, nil
+}
+
+// client cria o api.Client do perfil em uso
+func (e *env) client() (*api.Client, config.Profile, error) {
+	p, err := e.resolveProfile()
+	if err != nil {
+		return nil, p, err
+	}
+	c, err := p.NewClient()
+	return c, p, err
+}
+
+// sessionClient cria o api.Client do perfil em uso com a sessão salva pelo login
+func (e *env) sessionClient() (*api.Client, error) {
+	c, p, err := e.client()
+	if err != nil {
+		return nil, err
+	}
+	s, err := store.LoadSession(p.Name)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, errNotLoggedIn
+	}
+	c.Session = *s
+	return c, nil
+}
+
+// printJSON escreve v como JSON numa linha
+func (e *env) printJSON(v any) error {
+	return json.NewEncoder(e.stdout).Encode(v)
+}
+
+// formatMessage formata uma mensagem numa linha de texto
+func formatMessage(msg data.Message) string {
+	sender := msg.SenderUsername
+	if sender == "" {
+		sender = msg.UserId
+	}
+	content := strings.ReplaceAll(msg.Content, "\n", " ")
+	return fmt.Sprintf("%s [%s] <%s> %s", msg.SentAt.Local().Format("2006-01-02 15:04:05"), msg.RoomId, sender, content)
+}
+
+func (e *env) runTUI() error {
+	var profile *config.Profile
+	if e.globals.profile != "" || e.apiURL != "" || e.wsURL != "" {
+		p, err := e.resolveProfile()
+		if err != nil {
+			return err
+		}
+		profile = &p
+	}
+
+	// A TUI ocupa o terminal: o log (ex.: eventos desconhecidos do websocket) vai para um arquivo
+	logFile, err := store.OpenLog()
+	if err != nil {
+		fmt.Fprintf(e.stderr, "AVISO: Falha ao abrir log: %v\n", err)
+		log.SetOutput(io.Discard)
+	} else {
+		defer logFile.Close()
+		log.SetOutput(logFile)
+	}
+
+	m := ui.NewModel(e.cfg, profile)
+	_, err = tea.NewProgram(m).Run()
+	return err
+}
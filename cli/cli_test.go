@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"flag"
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		args       []string
+		positional []string
+		limit      int
+		json       bool
+	}{
+		{[]string{"sala"}, []string{"sala"}, 0, false},
+		{[]string{"sala", "--limit", "10"}, []string{"sala"}, 10, false},
+		{[]string{"--limit=5", "sala", "--json"}, []string{"sala"}, 5, true},
+		{[]string{"a", "--json", "b"}, []string{"a", "b"}, 0, true},
+		{[]string{"sala", "--", "--limit", "3"}, []string{"sala", "--limit", "3"}, 0, false},
+		{nil, nil, 0, false},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("teste", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		limit := fs.Int("limit", 0, "")
+		asJSON := fs.Bool("json", false, "")
+
+		positional, err := parseArgs(fs, tt.args)
+		if err != nil {
+			t.Errorf("parseArgs(%q): %v", tt.args, err)
+			continue
+		}
+		if !slices.Equal(positional, tt.positional) || *limit != tt.limit || *asJSON != tt.json {
+			t.Errorf("parseArgs(%q) = %q, limit %d, json %v; esperado %q, limit %d, json %v",
+				tt.args, positional, *limit, *asJSON, tt.positional, tt.limit, tt.json)
+		}
+	}
+}
+
+func TestParseArgsUnknownFlag(t *testing.T) {
+	fs := flag.NewFlagSet("teste", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if _, err := parseArgs(fs, []string{"sala", "--nao-existe"}); err == nil {
+		t.Error("flag desconhecida não foi rejeitada")
+	}
+}
@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mellojp/chatli/data"
+	"github.com/mellojp/chatli/store"
+
+	"github.com/charmbracelet/x/term"
+)
+
+func (e *env) runLogin(args []string) error {
+	fs := e.flagSet("login")
+	username := fs.String("username", "", "nome de usuário (padrão: o do perfil)")
+	if _, err := e.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	c, p, err := e.client()
+	if err != nil {
+		return err
+	}
+	if *username == "" {
+		*username = p.Username
+	}
+	if *username == "" {
+		*username, err = e.prompt("Username: ")
+		if err != nil {
+			return err
+		}
+	}
+	password, err := e.readPassword("Password: ")
+	if err != nil {
+		return err
+	}
+
+	session, err := c.LoginContext(context.Background(), *username, password)
+	if err != nil {
+		return err
+	}
+	if err := store.SaveSession(p.Name, *session); err != nil {
+		return fmt.Errorf("falha ao salvar sessão: %w", err)
+	}
+
+	if e.json {
+		return e.printJSON(map[string]string{"username": session.Username, "user_id": session.UserId})
+	}
+	fmt.Fprintf(e.stdout, "Logado como %s\n", session.Username)
+	return nil
+}
+
+// prompt lê uma linha do stdin, exibindo a pergunta no stderr
+func (e *env) prompt(label string) (string, error) {
+	fmt.Fprint(e.stderr, label)
+	line, err := bufio.NewReader(e.stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("falha ao ler entrada: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readPassword lê a senha sem eco quando o stdin é um terminal; caso contrário lê uma linha
+func (e *env) readPassword(label string) (string, error) {
+	f, ok := e.stdin.(*os.File)
+	if !ok || !term.IsTerminal(f.Fd()) {
+		return e.prompt(label)
+	}
+	fmt.Fprint(e.stderr, label)
+	b, err := term.ReadPassword(f.Fd())
+	fmt.Fprintln(e.stderr)
+	if err != nil {
+		return "", fmt.Errorf("falha ao ler senha: %w", err)
+	}
+	return string(b), nil
+}
+
+func (e *env) runRoomsList(args []string) error {
+	fs := e.flagSet("rooms list")
+	if _, err := e.parse(fs, args, 0); err != nil {
+		return err
+	}
+	c, err := e.sessionClient()
+	if err != nil {
+		return err
+	}
+	rooms, err := c.GetUserRoomsContext(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if e.json {
+		if rooms == nil {
+			rooms = []data.Room{}
+		}
+		return e.printJSON(rooms)
+	}
+	for _, r := range rooms {
+		fmt.Fprintf(e.stdout, "%s\t%s\n", r.Id, r.Name)
+	}
+	return nil
+}
+
+func (e *env) runRoomsCreate(args []string) error {
+	fs := e.flagSet("rooms create")
+	rest, err := e.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	c, err := e.sessionClient()
+	if err != nil {
+		return err
+	}
+	room, err := c.CreateRoomContext(context.Background(), rest[0])
+	if err != nil {
+		return err
+	}
+
+	if e.json {
+		return e.printJSON(room)
+	}
+	fmt.Fprintf(e.stdout, "%s\t%s\n", room.Id, room.Name)
+	return nil
+}
+
+func (e *env) runRoomsJoin(args []string) error {
+	fs := e.flagSet("rooms join")
+	rest, err := e.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	c, err := e.sessionClient()
+	if err != nil {
+		return err
+	}
+	if err := c.JoinRoomContext(context.Background(), rest[0]); err != nil {
+		return err
+	}
+
+	if e.json {
+		return e.printJSON(map[string]string{"room_id": rest[0]})
+	}
+	fmt.Fprintf(e.stdout, "Entrou na sala %s\n", rest[0])
+	return nil
+}
+
+func (e *env) runHistory(args []string) error {
+	fs := e.flagSet("history")
+	limit := fs.Int("limit", 50, "quantidade máxima de mensagens")
+	before := fs.String("before", "", "mostra apenas mensagens anteriores a este id")
+	rest, err := e.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	c, err := e.sessionClient()
+	if err != nil {
+		return err
+	}
+	messages, err := c.LoadChatMessagesPageContext(context.Background(), rest[0], *before, *limit)
+	if err != nil {
+		return err
+	}
+
+	// Mais antigas primeiro, como na TUI
+	history := data.NewMessageStore()
+	history.Merge(messages)
+	for _, msg := range history.Messages() {
+		if e.json {
+			if err := e.printJSON(msg); err != nil {
+				return err
+			}
+			continue
+		}
+		fmt.Fprintln(e.stdout, formatMessage(msg))
+	}
+	return nil
+}
+
+func (e *env) runVersion(args []string) error {
+	fs := e.flagSet("version")
+	if _, err := e.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if e.json {
+		return e.printJSON(map[string]string{"version": Version})
+	}
+	fmt.Fprintf(e.stdout, "chatli %s\n", Version)
+	return nil
+}
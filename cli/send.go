@@ -0,0 +1,217 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mellojp/chatli/api"
+	"github.com/mellojp/chatli/data"
+)
+
+// errNoEcho indica que o servidor não devolveu a mensagem enviada dentro do prazo
+var errNoEcho = errors.New("o servidor não confirmou o envio da mensagem")
+
+// maxLineSize limita o tamanho de uma linha lida do stdin
+const maxLineSize = 1024 * 1024
+
+// runSend envia mensagens para uma sala: a do argumento ou, sem ele, as lidas do stdin
+// (uma por linha, enviadas conforme chegam, ou o conteúdo inteiro com --multiline). Cada
+// mensagem só é considerada entregue após o eco do servidor; qualquer falha encerra o
+// comando com erro.
+func (e *env) runSend(args []string) error {
+	fs := e.flagSet("send")
+	room := fs.String("room", "", "sala de destino")
+	multiline := fs.Bool("multiline", false, "envia todo o stdin como uma única mensagem")
+	timeout := fs.Duration("timeout", 10*time.Second, "tempo máximo de espera pela confirmação de cada mensagem")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if *room == "" {
+		if len(rest) == 0 {
+			return fmt.Errorf("%w: informe a sala com --room", errUsage)
+		}
+		*room, rest = rest[0], rest[1:]
+	}
+	if len(rest) > 1 {
+		return errUsage
+	}
+
+	// A mensagem do argumento e o corpo do --multiline são conhecidos antes de conectar;
+	// as linhas do stdin são lidas depois, para que "tail -f log | chatli send SALA" funcione
+	var contents []string
+	switch {
+	case len(rest) == 1:
+		contents = []string{rest[0]}
+	case *multiline:
+		if contents, err = readBody(e.stdin); err != nil {
+			return fmt.Errorf("falha ao ler stdin: %w", err)
+		}
+		if len(contents) == 0 {
+			return errors.New("nenhuma mensagem para enviar")
+		}
+	}
+
+	c, err := e.sessionClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	conn, err := c.Connect(ctx)
+	if err == nil {
+		// Nenhuma sala: só os ecos das mensagens enviadas interessam
+		err = conn.Subscribe(ctx)
+	}
+	cancel()
+	if err != nil {
+		if conn != nil {
+			conn.Close()
+		}
+		return err
+	}
+	defer conn.Close()
+	confirm := newConfirmer(conn)
+
+	sent := 0
+	send := func(content string) error {
+		msg := data.Message{
+			Type:           "chat",
+			UserId:         c.Session.UserId,
+			SenderUsername: c.Session.Username,
+			Content:        content,
+			RoomId:         *room,
+			SentAt:         time.Now(),
+			Nonce:          data.NewNonce(),
+		}
+		echo, err := confirm.send(msg, *timeout)
+		if err != nil {
+			return err
+		}
+		sent++
+
+		if e.json {
+			return e.printJSON(echo)
+		}
+		_, err = fmt.Fprintln(e.stdout, formatMessage(echo))
+		return err
+	}
+
+	if contents == nil {
+		err = eachLine(e.stdin, send)
+	} else {
+		for _, content := range contents {
+			if err = send(content); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if sent == 0 {
+		return errors.New("nenhuma mensagem para enviar")
+	}
+	return nil
+}
+
+// confirmer lê continuamente os eventos da conexão e entrega a cada envio o seu eco.
+// A leitura não pode parar enquanto o comando espera a próxima linha do stdin: sem ela,
+// os pongs deixam de ser processados e a conexão cai.
+type confirmer struct {
+	conn *api.Conn
+
+	mu      sync.Mutex
+	pending map[string]chan data.Message
+}
+
+func newConfirmer(conn *api.Conn) *confirmer {
+	c := &confirmer{conn: conn, pending: make(map[string]chan data.Message)}
+	go c.run()
+	return c
+}
+
+func (c *confirmer) run() {
+	for ev := range c.conn.Events() {
+		created, ok := ev.(data.MessageCreated)
+		if !ok || created.Nonce == "" {
+			continue
+		}
+		c.mu.Lock()
+		ch := c.pending[created.Nonce]
+		delete(c.pending, created.Nonce)
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- created.Message
+		}
+	}
+}
+
+// send envia a mensagem e aguarda o eco do servidor com o mesmo nonce
+func (c *confirmer) send(msg data.Message, timeout time.Duration) (data.Message, error) {
+	echo := make(chan data.Message, 1)
+	c.mu.Lock()
+	c.pending[msg.Nonce] = echo
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, msg.Nonce)
+		c.mu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := c.conn.Send(ctx, msg); err != nil {
+		return data.Message{}, err
+	}
+	select {
+	case m := <-echo:
+		return m, nil
+	case <-c.conn.Done():
+		if err := c.conn.Err(); err != nil {
+			return data.Message{}, err
+		}
+		return data.Message{}, errNoEcho
+	case <-ctx.Done():
+		return data.Message{}, errNoEcho
+	}
+}
+
+// eachLine chama fn para cada linha lida, assim que ela chega, ignorando as linhas em branco
+func eachLine(r io.Reader, fn func(string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("falha ao ler stdin: %w", err)
+	}
+	return nil
+}
+
+// readBody lê todo o conteúdo como uma única mensagem
+func readBody(r io.Reader) ([]string, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	body := strings.TrimRight(string(b), "\r\n")
+	if strings.TrimSpace(body) == "" {
+		return nil, nil
+	}
+	return []string{body}, nil
+}
@@ -0,0 +1,272 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mellojp/chatli/api"
+	"github.com/mellojp/chatli/data"
+)
+
+const (
+	// tailPageSize é o tamanho da página usada para recuperar mensagens perdidas durante uma reconexão
+	tailPageSize = 50
+	// maxTailPages limita quantas páginas são buscadas para cobrir uma queda
+	maxTailPages = 20
+	// tailSeenSize é quantos ids recentes cada sala guarda para descartar repetições: cobre um
+	// backfill completo, que é o único trecho que pode se sobrepor às mensagens ao vivo
+	tailSeenSize = maxTailPages * tailPageSize
+)
+
+// roomsFlag acumula os valores de uma flag repetida (--room A --room B)
+type roomsFlag []string
+
+func (r *roomsFlag) String() string { return strings.Join(*r, ",") }
+
+func (r *roomsFlag) Set(v string) error {
+	for _, id := range strings.Split(v, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			*r = append(*r, id)
+		}
+	}
+	return nil
+}
+
+// tailer acompanha as salas e imprime cada mensagem uma única vez
+type tailer struct {
+	*env
+	client *api.Client
+	format string
+	rooms  map[string]bool
+	// from é, por sala, desde quando ela é acompanhada: o backfill após reconectar começa
+	// na última mensagem impressa ou, se nenhuma foi, nesse horário
+	from map[string]time.Time
+	// seen guarda os ids das últimas mensagens impressas de cada sala
+	seen map[string]*recentIds
+	// last é a última mensagem impressa de cada sala
+	last map[string]*data.Message
+}
+
+func (e *env) runTail(args []string) error {
+	fs := e.flagSet("tail")
+	var rooms roomsFlag
+	fs.Var(&rooms, "room", "sala a acompanhar (pode ser repetida)")
+	format := fs.String("format", "text", "formato da saída: text ou json (NDJSON)")
+	backfill := fs.Int("backfill", 0, "imprime as últimas N mensagens de cada sala antes de acompanhar")
+	if _, err := e.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if len(rooms) == 0 {
+		return fmt.Errorf("%w: informe ao menos uma --room", errUsage)
+	}
+	if e.json {
+		*format = "json"
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("%w: formato desconhecido: %s", errUsage, *format)
+	}
+
+	c, err := e.sessionClient()
+	if err != nil {
+		return err
+	}
+
+	t := &tailer{
+		env:    e,
+		client: c,
+		format: *format,
+		rooms:  make(map[string]bool),
+		from:   make(map[string]time.Time),
+		seen:   make(map[string]*recentIds),
+		last:   make(map[string]*data.Message),
+	}
+	now := time.Now()
+	for _, id := range rooms {
+		t.rooms[id] = true
+		t.from[id] = now
+		t.seen[id] = newRecentIds(tailSeenSize)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *backfill > 0 {
+		for _, id := range rooms {
+			page, err := c.LoadChatMessagesPageContext(ctx, id, "", *backfill)
+			if err != nil {
+				return fmt.Errorf("sala %s: %w", id, err)
+			}
+			if err := t.printAll(page); err != nil {
+				return err
+			}
+		}
+	}
+	return t.follow(ctx)
+}
+
+// follow mantém o websocket aberto, reconectando com backoff até o contexto ser cancelado
+func (t *tailer) follow(ctx context.Context) error {
+	backoff := api.NewBackoff()
+	for {
+		conn, err := t.client.Connect(ctx)
+		if err == nil {
+			backoff.Reset()
+			err = t.stream(ctx, conn)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		wait := backoff.Next()
+		fmt.Fprintf(t.stderr, "chatli: conexão perdida (%v), reconectando em %s\n", err, wait.Round(time.Millisecond))
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// stream imprime as mensagens das salas acompanhadas até a conexão cair
+func (t *tailer) stream(ctx context.Context, conn *api.Conn) error {
+	defer conn.Close()
+
+	// Só os eventos das salas acompanhadas interessam
+	if err := conn.Subscribe(ctx, slices.Sorted(maps.Keys(t.rooms))...); err != nil {
+		return err
+	}
+
+	// Recupera o que chegou enquanto estávamos desconectados (ou, na primeira conexão,
+	// entre o início do comando e a inscrição)
+	for id := range t.rooms {
+		from := t.from[id]
+		if last := t.last[id]; last != nil && last.SentAt.After(from) {
+			from = last.SentAt
+		}
+		missed, err := t.since(ctx, id, from)
+		if err != nil {
+			return err
+		}
+		if err := t.printAll(missed); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case ev, ok := <-conn.Events():
+			if !ok {
+				return conn.Err()
+			}
+			// Apenas mensagens novas interessam ao tail
+			created, ok := ev.(data.MessageCreated)
+			if !ok || !t.rooms[created.RoomId] {
+				continue
+			}
+			if err := t.print(created.Message); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// since busca, página a página (até maxTailPages), as mensagens de uma sala enviadas a partir de from.
+// Mensagens com o mesmo horário de from entram: as já impressas são descartadas por print.
+func (t *tailer) since(ctx context.Context, roomId string, from time.Time) ([]data.Message, error) {
+	var all []data.Message
+	before := ""
+	for i := 0; i < maxTailPages; i++ {
+		page, err := t.client.LoadChatMessagesPageContext(ctx, roomId, before, tailPageSize)
+		if err != nil {
+			return nil, err
+		}
+		all = append(page, all...)
+		if len(page) < tailPageSize {
+			break
+		}
+		oldest := page[0]
+		if oldest.SentAt.Before(from) || oldest.Id == "" {
+			break
+		}
+		before = oldest.Id
+	}
+	return slices.DeleteFunc(all, func(msg data.Message) bool {
+		return msg.SentAt.Before(from)
+	}), nil
+}
+
+// printAll imprime em ordem as mensagens ainda não vistas
+func (t *tailer) printAll(messages []data.Message) error {
+	ordered := data.NewMessageStore()
+	ordered.Merge(messages)
+	for _, msg := range ordered.Messages() {
+		if err := t.print(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// print escreve a mensagem numa linha, ignorando as que já foram impressas
+func (t *tailer) print(msg data.Message) error {
+	seen := t.seen[msg.RoomId]
+	if seen == nil {
+		seen = newRecentIds(tailSeenSize)
+		t.seen[msg.RoomId] = seen
+	}
+	if !seen.add(msg) {
+		return nil
+	}
+	if last := t.last[msg.RoomId]; last == nil || msg.SentAt.After(last.SentAt) {
+		t.last[msg.RoomId] = &msg
+	}
+
+	if t.format == "json" {
+		return t.printJSON(msg)
+	}
+	_, err := fmt.Fprintln(t.stdout, formatMessage(msg))
+	return err
+}
+
+// recentIds é um conjunto limitado dos ids (ou nonces) das mensagens mais recentes:
+// ao passar do tamanho, o mais antigo é esquecido
+type recentIds struct {
+	size  int
+	ids   map[string]bool
+	order []string
+}
+
+func newRecentIds(size int) *recentIds {
+	return &recentIds{size: size, ids: make(map[string]bool)}
+}
+
+// add registra a mensagem e informa se ela ainda não tinha sido vista.
+// Mensagens sem Id nem Nonce não podem ser comparadas e são sempre novas.
+func (r *recentIds) add(msg data.Message) bool {
+	key := msg.Id
+	if key == "" {
+		key = "nonce:" + msg.Nonce
+	}
+	if key == "nonce:" {
+		return true
+	}
+	if r.ids[key] {
+		return false
+	}
+	r.ids[key] = true
+	r.order = append(r.order, key)
+	if len(r.order) > r.size {
+		delete(r.ids, r.order[0])
+		r.order = r.order[1:]
+	}
+	return true
+}
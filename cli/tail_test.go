@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/mellojp/chatli/data"
+)
+
+func TestRecentIds(t *testing.T) {
+	r := newRecentIds(3)
+	for i := range 5 {
+		if !r.add(data.Message{Id: strconv.Itoa(i)}) {
+			t.Fatalf("mensagem %d tratada como repetida", i)
+		}
+	}
+	if r.add(data.Message{Id: "4"}) || r.add(data.Message{Id: "2"}) {
+		t.Error("ids recentes não foram reconhecidos como repetidos")
+	}
+	// Os mais antigos foram esquecidos, mantendo o conjunto limitado
+	if len(r.ids) != 3 || len(r.order) != 3 {
+		t.Errorf("conjunto com %d ids e %d na fila, esperado 3", len(r.ids), len(r.order))
+	}
+	if !r.add(data.Message{Id: "0"}) {
+		t.Error("id esquecido continuou marcado como visto")
+	}
+
+	if !r.add(data.Message{Nonce: "n1"}) || r.add(data.Message{Nonce: "n1"}) {
+		t.Error("mensagem sem Id não foi comparada pelo Nonce")
+	}
+	if !r.add(data.Message{}) || !r.add(data.Message{}) {
+		t.Error("mensagem sem Id nem Nonce deveria ser sempre impressa")
+	}
+}
@@ -0,0 +1,105 @@
+// chatli-conformance confere se um servidor segue o contrato REST/websocket do chatli.
+//
+//	go run ./cmd/chatli-conformance -api-url http://localhost:8080
+//	go run ./cmd/chatli-conformance -dump openapi > openapi.json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/mellojp/chatli/api"
+	"github.com/mellojp/chatli/protocol"
+)
+
+func main() {
+	apiURL := flag.String("api-url", "http://localhost:8080", "URL da API do servidor testado")
+	wsURL := flag.String("ws-url", "", "URL do websocket (padrão: derivada da URL da API)")
+	timeout := flag.Duration("timeout", 10*time.Second, "tempo máximo de cada caso")
+	asJSON := flag.Bool("json", false, "relatório em JSON (um resultado por linha)")
+	insecure := flag.Bool("insecure", false, "não verifica o certificado TLS do servidor")
+	dump := flag.String("dump", "", "imprime a especificação embutida (openapi ou ws-schema) e sai")
+	flag.Parse()
+
+	switch *dump {
+	case "":
+	case "openapi":
+		os.Stdout.Write(protocol.OpenAPI)
+		return
+	case "ws-schema":
+		os.Stdout.Write(protocol.WSSchema)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "-dump desconhecido: %s\n", *dump)
+		os.Exit(2)
+	}
+
+	client := api.NewClient(*apiURL)
+	if *insecure {
+		if err := client.SetTLS(api.TLSOptions{InsecureSkipVerify: true}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	runner := &protocol.Runner{
+		BaseURL: *apiURL,
+		WSURL:   *wsURL,
+		Client:  client,
+		Timeout: *timeout,
+		OnResult: func(res protocol.Result) {
+			if *asJSON {
+				printJSON(res)
+				return
+			}
+			printText(res)
+		},
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	report, err := runner.Run(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if !*asJSON {
+		fmt.Printf("\n%d casos, %d falhas\n", len(report.Results), report.Failed())
+	}
+	if report.Failed() > 0 {
+		os.Exit(1)
+	}
+}
+
+func printText(res protocol.Result) {
+	status := "ok  "
+	switch {
+	case res.Skipped():
+		status = "skip"
+	case !res.Passed():
+		status = "FAIL"
+	}
+	fmt.Printf("%s %s (%s)\n", status, res.Name, res.Duration.Round(time.Millisecond))
+	if !res.Passed() {
+		fmt.Printf("     %v\n", res.Err)
+	}
+}
+
+func printJSON(res protocol.Result) {
+	out := map[string]any{
+		"name":        res.Name,
+		"passed":      res.Passed(),
+		"skipped":     res.Skipped(),
+		"duration_ms": res.Duration.Milliseconds(),
+	}
+	if res.Err != nil {
+		out["error"] = res.Err.Error()
+	}
+	json.NewEncoder(os.Stdout).Encode(out)
+}
@@ -0,0 +1,46 @@
+// chatli-server é o servidor de referência do chatli, com todo o estado em memória.
+//
+//	go run ./cmd/chatli-server -addr :8080
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mellojp/chatli/server"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "endereço de escuta")
+	ttl := flag.Duration("token-ttl", server.DefaultTokenTTL, "validade dos tokens emitidos")
+	flag.Parse()
+
+	srv := server.New(server.Options{
+		// Sem CHATLI_SERVER_SECRET, os tokens deixam de valer quando o servidor reinicia
+		Secret:   []byte(os.Getenv("CHATLI_SERVER_SECRET")),
+		TokenTTL: *ttl,
+	})
+	httpSrv := &http.Server{Addr: *addr, Handler: srv}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpSrv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("chatli-server escutando em %s", *addr)
+	if err := httpSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,61 @@
+// echobot é um exemplo de bot: repete as mensagens recebidas e responde aos comandos !echo e !ping.
+//
+//	BOT_USERNAME=echo BOT_PASSWORD=secret API_URL=http://localhost:8080 go run ./cmd/echobot -rooms sala1,sala2
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/mellojp/chatli/api"
+	"github.com/mellojp/chatli/bot"
+	"github.com/mellojp/chatli/data"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	rooms := flag.String("rooms", "", "salas atendidas, separadas por vírgula (padrão: todas)")
+	repeat := flag.Bool("repeat", false, "repete toda mensagem, não apenas os comandos")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("AVISO: Falha ao carregar .env: %v\n", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	b := bot.New(api.DefaultClient())
+	if *rooms != "" {
+		b.Rooms = strings.Split(*rooms, ",")
+	}
+
+	b.Command("ping", func(ctx context.Context, msg data.Message, args []string) error {
+		return b.Reply(ctx, msg, "pong")
+	})
+	b.Command("echo", func(ctx context.Context, msg data.Message, args []string) error {
+		return b.Reply(ctx, msg, strings.Join(args, " "))
+	})
+	if *repeat {
+		b.OnMessage(func(ctx context.Context, msg data.Message) error {
+			if strings.HasPrefix(msg.Content, bot.DefaultPrefix) {
+				return nil
+			}
+			return b.Reply(ctx, msg, msg.SenderUsername+" disse: "+msg.Content)
+		})
+	}
+
+	if err := b.Login(ctx, os.Getenv("BOT_USERNAME"), os.Getenv("BOT_PASSWORD")); err != nil {
+		log.Fatalf("login falhou: %v", err)
+	}
+	log.Printf("echobot conectado como %s", b.Session().Username)
+	if err := b.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,195 @@
+// Package config lê o arquivo de configuração do chatli com os perfis de servidor.
+//
+// Exemplo de config.json:
+//
+//	{
+//	  "default_profile": "prod",
+//	  "profiles": {
+//	    "prod":    {"api_url": "https://chat.example.com", "username": "ana"},
+//	    "staging": {"api_url": "https://staging.chat.example.com"},
+//	    "dev":     {"api_url": "https://localhost:8443", "tls": {"insecure_skip_verify": true}},
+//	    "corp":    {"api_url": "https://chat.corp.example", "proxy": "socks5://proxy.corp:1080"}
+//	  }
+//	}
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mellojp/chatli/api"
+)
+
+// TLS configura a verificação do servidor e o certificado do cliente de um perfil
+type TLS struct {
+	// CAFile é um bundle PEM de CAs adicionais para validar o servidor
+	CAFile string `json:"ca_file,omitempty"`
+	// CertFile e KeyFile são o certificado de cliente (mTLS)
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+	// InsecureSkipVerify desativa a verificação do certificado (apenas para desenvolvimento local)
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+}
+
+// Profile descreve um servidor chatli e como acessá-lo
+type Profile struct {
+	Name     string `json:"-"`
+	APIURL   string `json:"api_url"`
+	WSURL    string `json:"ws_url,omitempty"`
+	Username string `json:"username,omitempty"`
+	TLS      TLS    `json:"tls"`
+	// Proxy é usado pela API e pelo websocket (http://, socks5:// ou socks5h://);
+	// vazio usa HTTPS_PROXY/NO_PROXY do ambiente. NoProxy segue o formato de NO_PROXY.
+	Proxy   string `json:"proxy,omitempty"`
+	NoProxy string `json:"no_proxy,omitempty"`
+}
+
+// ErrNoProfile indica que há vários perfis configurados e nenhum foi escolhido
+var ErrNoProfile = errors.New("vários perfis configurados e nenhum padrão: escolha um com --profile")
+
+// Config é o conteúdo do arquivo de configuração
+type Config struct {
+	// DefaultProfile é usado quando nenhum perfil é escolhido explicitamente
+	DefaultProfile string             `json:"default_profile,omitempty"`
+	Profiles       map[string]Profile `json:"profiles"`
+}
+
+// Path retorna o caminho do arquivo de configuração, seguindo o XDG Base Directory
+// ($XDG_CONFIG_HOME/chatli/config.json ou ~/.config/chatli/config.json).
+// A variável CHATLI_CONFIG tem precedência.
+func Path() (string, error) {
+	if path := os.Getenv("CHATLI_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "chatli", "config.json"), nil
+}
+
+// Load lê o arquivo de configuração; um arquivo inexistente resulta numa Config sem perfis
+func Load() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return &Config{}, err
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return &Config{}, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return &Config{}, fmt.Errorf("config %s: %w", path, err)
+	}
+	for name, p := range cfg.Profiles {
+		p.Name = name
+		cfg.Profiles[name] = p
+	}
+	return &cfg, nil
+}
+
+// Names retorna os nomes dos perfis em ordem alfabética
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile busca um perfil pelo nome
+func (c *Config) Profile(name string) (Profile, bool) {
+	p, ok := c.Profiles[name]
+	return p, ok
+}
+
+// Options converte a configuração do perfil nas opções de TLS do pacote api
+func (t TLS) Options() api.TLSOptions {
+	return api.TLSOptions{
+		CAFile:             t.CAFile,
+		CertFile:           t.CertFile,
+		KeyFile:            t.KeyFile,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+}
+
+// Default retorna o perfil a usar sem escolha explícita: o DefaultProfile, o único perfil
+// configurado, ou o perfil anônimo baseado em API_URL/WS_URL quando não há perfis.
+// ok é false quando há vários perfis e nenhum padrão, cabendo ao usuário escolher.
+func (c *Config) Default() (p Profile, ok bool) {
+	if p, ok := c.Profiles[c.DefaultProfile]; ok {
+		return p, true
+	}
+	switch len(c.Profiles) {
+	case 0:
+		return anonymous(), true
+	case 1:
+		return c.Profiles[c.Names()[0]], true
+	}
+	return Profile{}, false
+}
+
+// anonymous é o perfil usado sem arquivo de configuração: URLs de API_URL/WS_URL
+// (aplicadas pelo pacote api) e TLS das variáveis TLS_*
+func anonymous() Profile {
+	o := api.TLSFromEnv()
+	return Profile{
+		TLS: TLS{
+			CAFile:             o.CAFile,
+			CertFile:           o.CertFile,
+			KeyFile:            o.KeyFile,
+			InsecureSkipVerify: o.InsecureSkipVerify,
+		},
+	}
+}
+
+// Resolve retorna o perfil pelo nome ou, com nome vazio, o perfil padrão
+func (c *Config) Resolve(name string) (Profile, error) {
+	if name != "" {
+		p, ok := c.Profile(name)
+		if !ok {
+			return Profile{}, fmt.Errorf("perfil desconhecido: %s", name)
+		}
+		return p, nil
+	}
+	if p, ok := c.Default(); ok {
+		return p, nil
+	}
+	return Profile{}, ErrNoProfile
+}
+
+// NewClient cria um api.Client apontando para o servidor do perfil, com as opções de TLS
+// aplicadas à API e ao websocket. Sem api_url, valem API_URL/WS_URL; sem ws_url, o
+// websocket é derivado de api_url. Em caso de erro no TLS, o Client é retornado sem ele.
+func (p Profile) NewClient() (*api.Client, error) {
+	c := api.DefaultClient()
+	if p.APIURL != "" {
+		c = api.NewClient(p.APIURL)
+	}
+	if p.WSURL != "" {
+		c.WSURL = strings.TrimRight(p.WSURL, "/")
+	}
+	if p.Proxy != "" {
+		if err := c.SetProxy(p.Proxy, p.NoProxy); err != nil {
+			return c, fmt.Errorf("perfil %q: %w", p.Name, err)
+		}
+	}
+	if opts := p.TLS.Options(); !opts.IsZero() {
+		if err := c.SetTLS(opts); err != nil {
+			return c, fmt.Errorf("perfil %q: %w", p.Name, err)
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,223 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// Tipos de evento enviados pelo servidor no websocket
+const (
+	EventMessageCreated = "message.created"
+	EventMessageEdited  = "message.edited"
+	EventMessageDeleted = "message.deleted"
+	EventMemberJoined   = "member.joined"
+	EventMemberLeft     = "member.left"
+	EventTyping         = "typing"
+	EventPresence       = "presence"
+	EventRoomRenamed    = "room.renamed"
+	EventSystemNotice   = "system.notice"
+	EventRoomUnread     = "room.unread"
+
+	// Frames enviados pelo cliente para escolher de quais salas recebe eventos
+	EventSubscribe   = "subscribe"
+	EventUnsubscribe = "unsubscribe"
+
+	// legacyChatType é o type dos frames antigos, que trazem a Message direto, sem envelope
+	legacyChatType = "chat"
+)
+
+// Envelope é o frame de evento do websocket: o tipo e o payload correspondente
+type Envelope struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Event é um evento decodificado de um Envelope
+type Event interface {
+	EventType() string
+}
+
+// MessageCreated é uma mensagem nova numa sala
+type MessageCreated struct {
+	Message
+}
+
+// MessageEdited indica que o conteúdo de uma mensagem mudou
+type MessageEdited struct {
+	Id       string    `json:"id"`
+	RoomId   string    `json:"room_id"`
+	Content  string    `json:"content"`
+	EditedAt time.Time `json:"edited_at"`
+}
+
+// MessageDeleted indica que uma mensagem foi apagada
+type MessageDeleted struct {
+	Id     string `json:"id"`
+	RoomId string `json:"room_id"`
+}
+
+// MemberJoined indica que um usuário entrou numa sala
+type MemberJoined struct {
+	RoomId   string `json:"room_id"`
+	UserId   string `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// MemberLeft indica que um usuário saiu de uma sala
+type MemberLeft struct {
+	RoomId   string `json:"room_id"`
+	UserId   string `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// Typing indica que um usuário começou ou parou de digitar numa sala
+type Typing struct {
+	RoomId   string `json:"room_id"`
+	UserId   string `json:"user_id,omitempty"`
+	Username string `json:"username,omitempty"`
+	Typing   bool   `json:"typing"`
+}
+
+// Status de presença
+const (
+	PresenceOnline  = "online"
+	PresenceAway    = "away"
+	PresenceOffline = "offline"
+)
+
+// Presence informa o status de um usuário
+type Presence struct {
+	UserId   string     `json:"user_id"`
+	Username string     `json:"username"`
+	Status   string     `json:"status"`
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+}
+
+// RoomRenamed indica que uma sala mudou de nome
+type RoomRenamed struct {
+	RoomId string `json:"room_id"`
+	Name   string `json:"name"`
+}
+
+// SystemNotice é um aviso do servidor, para uma sala ou, sem RoomId, para o usuário
+type SystemNotice struct {
+	RoomId string `json:"room_id,omitempty"`
+	// Level é "info", "warning" ou "error"
+	Level string    `json:"level"`
+	Text  string    `json:"text"`
+	At    time.Time `json:"at,omitempty"`
+}
+
+// RoomUnread substitui os eventos de uma sala em que a conexão não está inscrita:
+// informa quantas mensagens chegaram desde a última vez que o usuário a acompanhou
+// e quantas delas o citam (@username)
+type RoomUnread struct {
+	RoomId        string    `json:"room_id"`
+	Unread        int       `json:"unread"`
+	Mentions      int       `json:"mentions,omitempty"`
+	LastMessageAt time.Time `json:"last_message_at,omitempty"`
+}
+
+// Subscribe pede os eventos das salas informadas. A primeira inscrição tira a conexão do
+// modo global: a partir dela, salas fora da lista chegam apenas como RoomUnread.
+// Uma lista vazia apenas ativa esse modo. Read informa, por sala, o horário da última
+// mensagem lida: na primeira inscrição o servidor conta as não lidas a partir dele.
+type Subscribe struct {
+	RoomIds []string             `json:"room_ids"`
+	Read    map[string]time.Time `json:"read,omitempty"`
+}
+
+// Unsubscribe deixa de receber os eventos das salas informadas
+type Unsubscribe struct {
+	RoomIds []string `json:"room_ids"`
+}
+
+// UnknownEvent é um frame de tipo desconhecido ou que não pôde ser decodificado
+type UnknownEvent struct {
+	Type string
+	Raw  json.RawMessage
+	Err  error
+}
+
+func (MessageCreated) EventType() string { return EventMessageCreated }
+func (MessageEdited) EventType() string  { return EventMessageEdited }
+func (MessageDeleted) EventType() string { return EventMessageDeleted }
+func (MemberJoined) EventType() string   { return EventMemberJoined }
+func (MemberLeft) EventType() string     { return EventMemberLeft }
+func (Typing) EventType() string         { return EventTyping }
+func (Presence) EventType() string       { return EventPresence }
+func (RoomRenamed) EventType() string    { return EventRoomRenamed }
+func (SystemNotice) EventType() string   { return EventSystemNotice }
+func (RoomUnread) EventType() string     { return EventRoomUnread }
+func (Subscribe) EventType() string      { return EventSubscribe }
+func (Unsubscribe) EventType() string    { return EventUnsubscribe }
+func (e UnknownEvent) EventType() string { return e.Type }
+
+// decoders cria o valor de cada tipo de evento conhecido
+var decoders = map[string]func(payload []byte) (Event, error){
+	EventMessageCreated: decodeAs[MessageCreated],
+	EventMessageEdited:  decodeAs[MessageEdited],
+	EventMessageDeleted: decodeAs[MessageDeleted],
+	EventMemberJoined:   decodeAs[MemberJoined],
+	EventMemberLeft:     decodeAs[MemberLeft],
+	EventTyping:         decodeAs[Typing],
+	EventPresence:       decodeAs[Presence],
+	EventRoomRenamed:    decodeAs[RoomRenamed],
+	EventSystemNotice:   decodeAs[SystemNotice],
+	EventRoomUnread:     decodeAs[RoomUnread],
+	EventSubscribe:      decodeAs[Subscribe],
+	EventUnsubscribe:    decodeAs[Unsubscribe],
+}
+
+// EventTypes lista, em ordem alfabética, os tipos de evento que DecodeEvent conhece
+func EventTypes() []string {
+	return slices.Sorted(maps.Keys(decoders))
+}
+
+func decodeAs[T Event](payload []byte) (Event, error) {
+	var e T
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// DecodeEvent decodifica um frame do websocket. Frames antigos, com a Message direto e
+// type "chat" (ou sem type), viram MessageCreated. Tipos desconhecidos e payloads inválidos
+// viram UnknownEvent, para que quem consome possa registrá-los em vez de descartá-los.
+func DecodeEvent(raw []byte) Event {
+	var env Envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return UnknownEvent{Raw: raw, Err: err}
+	}
+
+	if env.Type == legacyChatType || env.Type == "" {
+		var msg Message
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			return UnknownEvent{Type: env.Type, Raw: raw, Err: err}
+		}
+		return MessageCreated{Message: msg}
+	}
+
+	decode, ok := decoders[env.Type]
+	if !ok {
+		return UnknownEvent{Type: env.Type, Raw: raw}
+	}
+	e, err := decode(env.Payload)
+	if err != nil {
+		return UnknownEvent{Type: env.Type, Raw: raw, Err: fmt.Errorf("payload de %s inválido: %w", env.Type, err)}
+	}
+	return e
+}
+
+// NewEnvelope empacota o evento para envio
+func NewEnvelope(e Event) (Envelope, error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return Envelope{}, err
+	}
+	return Envelope{Type: e.EventType(), Payload: payload}, nil
+}
@@ -0,0 +1,61 @@
+package data
+
+import "testing"
+
+func TestDecodeEventLegacyChat(t *testing.T) {
+	for _, raw := range []string{
+		`{"type":"chat","id":"1","room_id":"sala","content":"oi","nonce":"n1"}`,
+		`{"id":"1","room_id":"sala","content":"oi","nonce":"n1"}`,
+	} {
+		ev, ok := DecodeEvent([]byte(raw)).(MessageCreated)
+		if !ok {
+			t.Fatalf("%s: evento %T, esperado MessageCreated", raw, DecodeEvent([]byte(raw)))
+		}
+		if ev.Id != "1" || ev.RoomId != "sala" || ev.Content != "oi" || ev.Nonce != "n1" {
+			t.Errorf("%s: mensagem = %+v", raw, ev.Message)
+		}
+	}
+}
+
+func TestDecodeEventEnvelope(t *testing.T) {
+	env, err := NewEnvelope(Typing{RoomId: "sala", Username: "bob", Typing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := []byte(`{"type":"typing","payload":` + string(env.Payload) + `}`)
+	ev, ok := DecodeEvent(raw).(Typing)
+	if !ok || ev.RoomId != "sala" || ev.Username != "bob" || !ev.Typing {
+		t.Fatalf("evento = %#v", DecodeEvent(raw))
+	}
+
+	created := DecodeEvent([]byte(`{"type":"message.created","payload":{"id":"2","room_id":"sala","content":"novo"}}`))
+	if msg, ok := created.(MessageCreated); !ok || msg.Id != "2" {
+		t.Errorf("message.created = %#v", created)
+	}
+}
+
+func TestDecodeEventUnknown(t *testing.T) {
+	tests := []struct {
+		raw     string
+		typ     string
+		wantErr bool
+	}{
+		{`{"type":"reaction.added","payload":{"emoji":"👍"}}`, "reaction.added", false},
+		{`{"type":"typing","payload":{"typing":"sim"}}`, "typing", true},
+		{`{"type":"chat","content":42}`, "chat", true},
+		{`não é json`, "", true},
+	}
+	for _, tt := range tests {
+		ev, ok := DecodeEvent([]byte(tt.raw)).(UnknownEvent)
+		if !ok {
+			t.Errorf("%s: evento %T, esperado UnknownEvent", tt.raw, DecodeEvent([]byte(tt.raw)))
+			continue
+		}
+		if ev.Type != tt.typ || (ev.Err != nil) != tt.wantErr || string(ev.Raw) != tt.raw {
+			t.Errorf("%s: UnknownEvent = %+v", tt.raw, ev)
+		}
+		if ev.EventType() != tt.typ {
+			t.Errorf("%s: EventType = %q", tt.raw, ev.EventType())
+		}
+	}
+}
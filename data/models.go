@@ -1,6 +1,9 @@
 package data
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
 	"time"
 )
 
@@ -12,6 +15,10 @@ type Message struct {
 	Content        string    `json:"content"`
 	SentAt         time.Time `json:"created_at,omitempty"`
 	RoomId         string    `json:"room_id"`
+	// Nonce é gerado pelo cliente e devolvido pelo servidor no eco, permitindo correlacionar o envio
+	Nonce string `json:"nonce,omitempty"`
+	// EditedAt é preenchido quando o conteúdo foi editado depois do envio
+	EditedAt *time.Time `json:"edited_at,omitempty"`
 }
 
 type Room struct {
@@ -27,4 +34,36 @@ type Session struct {
 	Username    string `json:"username"`
 	UserId      string `json:"user_id"`
 	JoinedRooms []Room `json:"joined_rooms"`
-}
\ No newline at end of file
+}
+
+// NewNonce gera um identificador aleatório para correlacionar uma mensagem com seu eco
+func NewNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Mentions informa se a mensagem cita o usuário com @username (sem diferenciar maiúsculas)
+func (msg Message) Mentions(username string) bool {
+	if username == "" {
+		return false
+	}
+	content := strings.ToLower(msg.Content)
+	mention := "@" + strings.ToLower(username)
+	for i := 0; ; {
+		j := strings.Index(content[i:], mention)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(mention)
+		if (start == 0 || !isNameByte(content[start-1])) && (end == len(content) || !isNameByte(content[end])) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+// isNameByte informa se o byte pode fazer parte de um username
+func isNameByte(b byte) bool {
+	return b == '_' || b == '-' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 0x80
+}
@@ -0,0 +1,27 @@
+package data
+
+import "testing"
+
+func TestMessageMentions(t *testing.T) {
+	tests := []struct {
+		content  string
+		username string
+		want     bool
+	}{
+		{"oi @bob", "bob", true},
+		{"@bob, tudo bem?", "bob", true},
+		{"fala @Bob.", "bob", true},
+		{"(@bob)", "BOB", true},
+		{"oi @bobby", "bob", false},
+		{"oi @bob_2", "bob", false},
+		{"mail@bob.com", "bob", false},
+		{"@bobby e @bob", "bob", true},
+		{"oi bob", "bob", false},
+		{"oi @", "", false},
+	}
+	for _, tt := range tests {
+		if got := (Message{Content: tt.content}).Mentions(tt.username); got != tt.want {
+			t.Errorf("Mentions(%q) em %q = %v, esperado %v", tt.username, tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package data
+
+import (
+	"sort"
+	"time"
+)
+
+// MessageStore guarda as mensagens de uma sala sem duplicatas e ordenadas por SentAt.
+// Duplicatas são detectadas pelo Id do servidor ou pelo Nonce gerado pelo cliente.
+type MessageStore struct {
+	msgs   []Message
+	ids    map[string]bool
+	nonces map[string]bool
+}
+
+// NewMessageStore cria um MessageStore vazio
+func NewMessageStore() *MessageStore {
+	return &MessageStore{
+		ids:    make(map[string]bool),
+		nonces: make(map[string]bool),
+	}
+}
+
+// Contains informa se a mensagem (pelo Id ou Nonce) já está no store
+func (s *MessageStore) Contains(msg Message) bool {
+	if s == nil {
+		return false
+	}
+	return (msg.Id != "" && s.ids[msg.Id]) || (msg.Nonce != "" && s.nonces[msg.Nonce])
+}
+
+// Add insere a mensagem na posição correspondente ao seu SentAt.
+// Retorna false se ela já estava no store.
+func (s *MessageStore) Add(msg Message) bool {
+	if s.Contains(msg) {
+		return false
+	}
+	if msg.Id != "" {
+		s.ids[msg.Id] = true
+	}
+	if msg.Nonce != "" {
+		s.nonces[msg.Nonce] = true
+	}
+
+	// Mensagens com o mesmo horário mantêm a ordem de chegada
+	i := sort.Search(len(s.msgs), func(i int) bool {
+		return s.msgs[i].SentAt.After(msg.SentAt)
+	})
+	s.msgs = append(s.msgs, Message{})
+	copy(s.msgs[i+1:], s.msgs[i:])
+	s.msgs[i] = msg
+	return true
+}
+
+// Merge adiciona várias mensagens e retorna quantas eram novas
+func (s *MessageStore) Merge(msgs []Message) int {
+	added := 0
+	for _, msg := range msgs {
+		if s.Add(msg) {
+			added++
+		}
+	}
+	return added
+}
+
+// Edit troca o conteúdo da mensagem com o Id informado. Retorna false se ela não está no store.
+func (s *MessageStore) Edit(id, content string, at time.Time) bool {
+	i := s.index(id)
+	if i < 0 {
+		return false
+	}
+	s.msgs[i].Content = content
+	s.msgs[i].EditedAt = &at
+	return true
+}
+
+// Remove apaga a mensagem com o Id informado. O Id continua conhecido, para que um eco
+// ou uma página de histórico atrasados não a tragam de volta.
+func (s *MessageStore) Remove(id string) bool {
+	i := s.index(id)
+	if i < 0 {
+		return false
+	}
+	s.msgs = append(s.msgs[:i], s.msgs[i+1:]...)
+	return true
+}
+
+func (s *MessageStore) index(id string) int {
+	if s == nil || id == "" {
+		return -1
+	}
+	for i := len(s.msgs) - 1; i >= 0; i-- {
+		if s.msgs[i].Id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Messages retorna as mensagens em ordem cronológica
+func (s *MessageStore) Messages() []Message {
+	if s == nil {
+		return nil
+	}
+	return s.msgs
+}
+
+// Len retorna a quantidade de mensagens no store
+func (s *MessageStore) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.msgs)
+}
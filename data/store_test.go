@@ -0,0 +1,79 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMessageStoreOrdersBySentAt(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewMessageStore()
+	s.Add(Message{Id: "2", SentAt: base.Add(2 * time.Minute)})
+	s.Add(Message{Id: "1", SentAt: base.Add(time.Minute)})
+	s.Add(Message{Id: "3", SentAt: base.Add(3 * time.Minute)})
+	// Mesmo horário: mantém a ordem de chegada
+	s.Add(Message{Id: "3b", SentAt: base.Add(3 * time.Minute)})
+
+	var got []string
+	for _, msg := range s.Messages() {
+		got = append(got, msg.Id)
+	}
+	want := []string{"1", "2", "3", "3b"}
+	if len(got) != len(want) {
+		t.Fatalf("ids = %v, esperado %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("ids = %v, esperado %v", got, want)
+		}
+	}
+}
+
+func TestMessageStoreDedupesByIdAndNonce(t *testing.T) {
+	s := NewMessageStore()
+	if !s.Add(Message{Id: "1", Nonce: "n1", Content: "oi"}) {
+		t.Fatal("primeira mensagem não foi adicionada")
+	}
+	if s.Add(Message{Id: "1", Content: "oi"}) {
+		t.Error("mensagem com Id repetido foi adicionada")
+	}
+	if s.Add(Message{Nonce: "n1", Content: "oi"}) {
+		t.Error("eco com Nonce repetido foi adicionado")
+	}
+	if n := s.Merge([]Message{{Id: "1"}, {Id: "2"}, {Id: "3"}}); n != 2 {
+		t.Errorf("Merge = %d, esperado 2", n)
+	}
+	if s.Len() != 3 {
+		t.Errorf("Len = %d, esperado 3", s.Len())
+	}
+}
+
+func TestMessageStoreEditAndRemove(t *testing.T) {
+	s := NewMessageStore()
+	s.Add(Message{Id: "1", Content: "antes"})
+	at := time.Now()
+	if !s.Edit("1", "depois", at) {
+		t.Fatal("Edit não encontrou a mensagem")
+	}
+	if msg := s.Messages()[0]; msg.Content != "depois" || msg.EditedAt == nil || !msg.EditedAt.Equal(at) {
+		t.Errorf("mensagem editada = %+v", msg)
+	}
+	if s.Edit("x", "nada", at) {
+		t.Error("Edit de mensagem inexistente retornou true")
+	}
+
+	if !s.Remove("1") || s.Len() != 0 {
+		t.Fatal("Remove não apagou a mensagem")
+	}
+	// O Id continua conhecido: um eco atrasado não a traz de volta
+	if s.Add(Message{Id: "1", Content: "antes"}) {
+		t.Error("mensagem removida voltou ao store")
+	}
+}
+
+func TestMessageStoreNil(t *testing.T) {
+	var s *MessageStore
+	if s.Contains(Message{Id: "1"}) || s.Len() != 0 || s.Messages() != nil {
+		t.Error("store nil deveria se comportar como vazio")
+	}
+}
@@ -1,13 +1,11 @@
 package main
 
 import (
-	"github.com/mellojp/chatli/ui"
+	"github.com/mellojp/chatli/cli"
 
-	"fmt"
 	"log"
 	"os"
 
-	tea "github.com/charmbracelet/bubbletea"
 	"github.com/joho/godotenv"
 )
 
@@ -16,10 +14,5 @@ func main() {
 		log.Printf("AVISO: Falha ao carregar .env: %v\n", err)
 	}
 
-	m := ui.NewModel()
-	p := tea.NewProgram(m)
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("There's been an error: %v", err)
-		os.Exit(1)
-	}
+	os.Exit(cli.Run(os.Args[1:]))
 }
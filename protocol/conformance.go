@@ -0,0 +1,723 @@
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/mellojp/chatli/api"
+	"github.com/mellojp/chatli/data"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrSkipped marca um caso que não pôde rodar, por depender de um caso que falhou
+// ou de um recurso opcional que o servidor não oferece
+var ErrSkipped = errors.New("ignorado")
+
+// Result é o resultado de um caso da suíte
+type Result struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+}
+
+// Passed informa se o caso passou
+func (r Result) Passed() bool { return r.Err == nil }
+
+// Skipped informa se o caso foi ignorado
+func (r Result) Skipped() bool { return errors.Is(r.Err, ErrSkipped) }
+
+// Report reúne os resultados de uma execução
+type Report struct {
+	Results []Result
+}
+
+// Failed conta os casos que falharam (ignorados não contam)
+func (r *Report) Failed() int {
+	n := 0
+	for _, res := range r.Results {
+		if !res.Passed() && !res.Skipped() {
+			n++
+		}
+	}
+	return n
+}
+
+// Runner executa a suíte de conformidade contra um servidor. Cada execução cria usuários e
+// uma sala com nomes únicos, então pode rodar contra servidores compartilhados.
+type Runner struct {
+	BaseURL string
+	// WSURL é derivada de BaseURL quando vazia
+	WSURL string
+	// Client é usado nas chamadas HTTP e no websocket (padrão: api.NewClient(BaseURL))
+	Client *api.Client
+	// Timeout limita cada caso (padrão 10s)
+	Timeout time.Duration
+	// OnResult, se definido, é chamado ao fim de cada caso
+	OnResult func(Result)
+
+	openapi *Document
+	ws      *Document
+	state   runState
+}
+
+// runState guarda o que os casos anteriores produziram
+type runState struct {
+	prefix   string
+	password string
+	tokenA   string
+	tokenB   string
+	userA    string
+	room     *data.Room
+	messages []data.Message
+}
+
+type testCase struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// Run executa todos os casos em ordem e retorna o relatório
+func (r *Runner) Run(ctx context.Context) (*Report, error) {
+	var err error
+	if r.openapi, err = LoadOpenAPI(); err != nil {
+		return nil, err
+	}
+	if r.ws, err = LoadWSSchema(); err != nil {
+		return nil, err
+	}
+	if r.Client == nil {
+		r.Client = api.NewClient(r.BaseURL)
+	}
+	if r.WSURL != "" {
+		r.Client.WSURL = r.WSURL
+	}
+	if r.Timeout <= 0 {
+		r.Timeout = 10 * time.Second
+	}
+	r.state = runState{prefix: "conformance-" + data.NewNonce()[:8], password: data.NewNonce()}
+
+	report := &Report{}
+	for _, tc := range r.cases() {
+		start := time.Now()
+		caseCtx, cancel := context.WithTimeout(ctx, r.Timeout)
+		err := tc.run(caseCtx)
+		cancel()
+
+		res := Result{Name: tc.name, Err: err, Duration: time.Since(start)}
+		report.Results = append(report.Results, res)
+		if r.OnResult != nil {
+			r.OnResult(res)
+		}
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+	}
+	return report, nil
+}
+
+func (r *Runner) cases() []testCase {
+	return []testCase{
+		{"os schemas publicados correspondem aos tipos Go", func(context.Context) error { return CheckTypes() }},
+		{"POST /auth/register cria usuários", r.testRegister},
+		{"POST /auth/register recusa username repetido com 409", r.testRegisterConflict},
+		{"POST /auth/login recusa senha errada com 401", r.testLoginInvalid},
+		{"POST /auth/login emite token JWT com exp", r.testLogin},
+		{"rotas autenticadas recusam requisições sem token com 401", r.testUnauthorized},
+		{"POST /rooms/create cria a sala e inclui o criador", r.testCreateRoom},
+		{"POST /rooms/join responde 404 para sala inexistente", r.testJoinUnknown},
+		{"POST /rooms/join adiciona o usuário à sala", r.testJoin},
+		{"GET /rooms/history retorna lista vazia numa sala nova", r.testEmptyHistory},
+		{"GET /ws recusa handshake sem token", r.testWSUnauthorized},
+		{"websocket ecoa a mensagem ao remetente com o nonce e entrega aos membros", r.testWSFanOut},
+		{"websocket com subscribe troca mensagens de outras salas por room.unread (opcional)", r.testWSSubscribe},
+		{"websocket responde ping com pong", r.testWSPing},
+		{"GET /rooms/history pagina com before e limit em ordem cronológica", r.testHistoryPaging},
+		{"POST /auth/refresh emite novo token (opcional)", r.testRefresh},
+		{"POST /auth/logout revoga o token", r.testLogout},
+	}
+}
+
+// call faz uma requisição HTTP e retorna o status e o corpo bruto
+func (r *Runner) call(ctx context.Context, method, path, token string, body any) (int, []byte, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return 0, nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.Client.BaseURL+path, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	httpClient := r.Client.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, raw, err
+}
+
+// expect confere o status e, se ref não for vazio, valida o corpo contra o schema
+func (r *Runner) expect(status int, raw []byte, ref string, want ...int) error {
+	if !slices.Contains(want, status) {
+		return fmt.Errorf("status %d, esperado %v: %s", status, want, bytes.TrimSpace(raw))
+	}
+	if ref == "" {
+		return nil
+	}
+	if err := r.openapi.Validate(ref, raw); err != nil {
+		return fmt.Errorf("corpo não segue %s: %w", ref, err)
+	}
+	return nil
+}
+
+// expectArray valida um corpo que deve ser uma lista de itens do schema
+func (r *Runner) expectArray(raw []byte, ref string) error {
+	var items []any
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return fmt.Errorf("esperada uma lista JSON: %w", err)
+	}
+	for i, item := range items {
+		if err := r.openapi.ValidateValue(ref, item); err != nil {
+			return fmt.Errorf("item %d não segue %s: %w", i, ref, err)
+		}
+	}
+	return nil
+}
+
+func (r *Runner) credentials(user string) map[string]string {
+	return map[string]string{"username": r.state.prefix + "-" + user, "password": r.state.password}
+}
+
+func (r *Runner) testRegister(ctx context.Context) error {
+	for _, u := range []string{"a", "b"} {
+		status, raw, err := r.call(ctx, "POST", "/auth/register", "", r.credentials(u))
+		if err != nil {
+			return err
+		}
+		if err := r.expect(status, raw, "", http.StatusCreated); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Runner) testRegisterConflict(ctx context.Context) error {
+	status, raw, err := r.call(ctx, "POST", "/auth/register", "", r.credentials("a"))
+	if err != nil {
+		return err
+	}
+	return r.expect(status, raw, ErrorSchema, http.StatusConflict)
+}
+
+func (r *Runner) testLoginInvalid(ctx context.Context) error {
+	creds := r.credentials("a")
+	creds["password"] = "wrong-" + creds["password"]
+	status, raw, err := r.call(ctx, "POST", "/auth/login", "", creds)
+	if err != nil {
+		return err
+	}
+	return r.expect(status, raw, "", http.StatusUnauthorized)
+}
+
+func (r *Runner) login(ctx context.Context, user string) (string, string, error) {
+	status, raw, err := r.call(ctx, "POST", "/auth/login", "", r.credentials(user))
+	if err != nil {
+		return "", "", err
+	}
+	if err := r.expect(status, raw, LoginResponseSchema, http.StatusOK); err != nil {
+		return "", "", err
+	}
+	var res map[string]string
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return "", "", err
+	}
+	if _, err := api.TokenExpiry(res["token"]); err != nil {
+		return "", "", fmt.Errorf("token sem exp legível: %w", err)
+	}
+	return res["token"], res["user_id"], nil
+}
+
+func (r *Runner) testLogin(ctx context.Context) error {
+	var err error
+	if r.state.tokenA, r.state.userA, err = r.login(ctx, "a"); err != nil {
+		return err
+	}
+	r.state.tokenB, _, err = r.login(ctx, "b")
+	return err
+}
+
+func (r *Runner) testUnauthorized(ctx context.Context) error {
+	for _, path := range []string{"/rooms", "/rooms/history?room_id=x"} {
+		status, raw, err := r.call(ctx, "GET", path, "", nil)
+		if err != nil {
+			return err
+		}
+		if err := r.expect(status, raw, "", http.StatusUnauthorized, http.StatusForbidden); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// roomsOf retorna as salas do usuário do token, validadas contra o schema
+func (r *Runner) roomsOf(ctx context.Context, token string) ([]data.Room, error) {
+	status, raw, err := r.call(ctx, "GET", "/rooms", token, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.expect(status, raw, "", http.StatusOK); err != nil {
+		return nil, err
+	}
+	if err := r.expectArray(raw, RoomSchema); err != nil {
+		return nil, err
+	}
+	var rooms []data.Room
+	return rooms, json.Unmarshal(raw, &rooms)
+}
+
+func hasRoom(rooms []data.Room, id string) bool {
+	return slices.ContainsFunc(rooms, func(room data.Room) bool { return room.Id == id })
+}
+
+func (r *Runner) testCreateRoom(ctx context.Context) error {
+	if r.state.tokenA == "" {
+		return ErrSkipped
+	}
+	name := r.state.prefix + "-room"
+	status, raw, err := r.call(ctx, "POST", "/rooms/create", r.state.tokenA, map[string]string{"room_name": name})
+	if err != nil {
+		return err
+	}
+	if err := r.expect(status, raw, RoomSchema, http.StatusOK, http.StatusCreated); err != nil {
+		return err
+	}
+	var room data.Room
+	if err := json.Unmarshal(raw, &room); err != nil {
+		return err
+	}
+	if room.Name != name {
+		return fmt.Errorf("nome da sala %q, esperado %q", room.Name, name)
+	}
+
+	rooms, err := r.roomsOf(ctx, r.state.tokenA)
+	if err != nil {
+		return err
+	}
+	if !hasRoom(rooms, room.Id) {
+		return fmt.Errorf("sala %s não aparece em GET /rooms do criador", room.Id)
+	}
+	r.state.room = &room
+	return nil
+}
+
+func (r *Runner) testJoinUnknown(ctx context.Context) error {
+	if r.state.tokenB == "" {
+		return ErrSkipped
+	}
+	status, raw, err := r.call(ctx, "POST", "/rooms/join", r.state.tokenB, map[string]string{"room_id": "missing-" + data.NewNonce()})
+	if err != nil {
+		return err
+	}
+	return r.expect(status, raw, "", http.StatusNotFound)
+}
+
+func (r *Runner) testJoin(ctx context.Context) error {
+	if r.state.tokenB == "" || r.state.room == nil {
+		return ErrSkipped
+	}
+	status, raw, err := r.call(ctx, "POST", "/rooms/join", r.state.tokenB, map[string]string{"room_id": r.state.room.Id})
+	if err != nil {
+		return err
+	}
+	if err := r.expect(status, raw, "", http.StatusOK, http.StatusCreated); err != nil {
+		return err
+	}
+	rooms, err := r.roomsOf(ctx, r.state.tokenB)
+	if err != nil {
+		return err
+	}
+	if !hasRoom(rooms, r.state.room.Id) {
+		return fmt.Errorf("sala %s não aparece em GET /rooms de quem entrou", r.state.room.Id)
+	}
+	return nil
+}
+
+// history busca uma página do histórico da sala de teste
+func (r *Runner) history(ctx context.Context, before string, limit int) ([]data.Message, error) {
+	query := url.Values{}
+	query.Set("room_id", r.state.room.Id)
+	if before != "" {
+		query.Set("before", before)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	status, raw, err := r.call(ctx, "GET", "/rooms/history?"+query.Encode(), r.state.tokenB, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.expect(status, raw, "", http.StatusOK); err != nil {
+		return nil, err
+	}
+	if err := r.expectArray(raw, MessageSchema); err != nil {
+		return nil, err
+	}
+	var messages []data.Message
+	return messages, json.Unmarshal(raw, &messages)
+}
+
+func (r *Runner) testEmptyHistory(ctx context.Context) error {
+	if r.state.room == nil {
+		return ErrSkipped
+	}
+	messages, err := r.history(ctx, "", 0)
+	if err != nil {
+		return err
+	}
+	if len(messages) != 0 {
+		return fmt.Errorf("sala nova com %d mensagens", len(messages))
+	}
+	return nil
+}
+
+// dial abre o websocket com o token informado
+func (r *Runner) dial(ctx context.Context, token string) (*websocket.Conn, error) {
+	c := *r.Client
+	c.Session = data.Session{Token: token}
+	return c.ConnectWebSocketContext(ctx)
+}
+
+func (r *Runner) testWSUnauthorized(ctx context.Context) error {
+	ws, err := r.dial(ctx, "")
+	if err == nil {
+		ws.Close()
+		return errors.New("handshake aceito sem token")
+	}
+	if !errors.Is(err, websocket.ErrBadHandshake) {
+		return fmt.Errorf("esperada recusa do handshake, recebido: %w", err)
+	}
+	return nil
+}
+
+// sendFrame valida o frame (mensagem ou envelope) contra ClientFrame e o escreve no socket
+func (r *Runner) sendFrame(ws *websocket.Conn, frame any) error {
+	raw, err := json.Marshal(frame)
+	if err != nil {
+		return err
+	}
+	if err := r.ws.Validate(ClientFrameSchema, raw); err != nil {
+		return fmt.Errorf("frame do cliente não segue o schema (erro na suíte): %w", err)
+	}
+	return ws.WriteMessage(websocket.TextMessage, raw)
+}
+
+// readEcho lê frames até encontrar a mensagem com o nonce informado, validando cada um contra ServerFrame
+func (r *Runner) readEcho(ctx context.Context, ws *websocket.Conn, nonce string) (data.Message, error) {
+	deadline, _ := ctx.Deadline()
+	ws.SetReadDeadline(deadline)
+	for {
+		_, raw, err := ws.ReadMessage()
+		if err != nil {
+			return data.Message{}, fmt.Errorf("eco não recebido: %w", err)
+		}
+		if err := r.ws.Validate(ServerFrameSchema, raw); err != nil {
+			return data.Message{}, fmt.Errorf("frame do servidor não segue o schema: %w", err)
+		}
+		// Outros eventos (presença, entrada de membros, ...) podem chegar antes do eco
+		if created, ok := data.DecodeEvent(raw).(data.MessageCreated); ok && created.Nonce == nonce {
+			return created.Message, nil
+		}
+	}
+}
+
+func (r *Runner) chatFrame(content string) data.Message {
+	return data.Message{
+		Type:    "chat",
+		UserId:  r.state.userA,
+		Content: content,
+		RoomId:  r.state.room.Id,
+		SentAt:  time.Now(),
+		Nonce:   data.NewNonce(),
+	}
+}
+
+func (r *Runner) testWSFanOut(ctx context.Context) error {
+	if r.state.room == nil || r.state.tokenB == "" {
+		return ErrSkipped
+	}
+	wsA, err := r.dial(ctx, r.state.tokenA)
+	if err != nil {
+		return fmt.Errorf("conexão do remetente: %w", err)
+	}
+	defer wsA.Close()
+	wsB, err := r.dial(ctx, r.state.tokenB)
+	if err != nil {
+		return fmt.Errorf("conexão do outro membro: %w", err)
+	}
+	defer wsB.Close()
+
+	msg := r.chatFrame("conformance fan-out")
+	if err := r.sendFrame(wsA, msg); err != nil {
+		return err
+	}
+	echo, err := r.readEcho(ctx, wsA, msg.Nonce)
+	if err != nil {
+		return fmt.Errorf("remetente: %w", err)
+	}
+	if echo.Content != msg.Content || echo.RoomId != msg.RoomId {
+		return fmt.Errorf("eco diferente do enviado: %+v", echo)
+	}
+	delivered, err := r.readEcho(ctx, wsB, msg.Nonce)
+	if err != nil {
+		return fmt.Errorf("outro membro: %w", err)
+	}
+	if delivered.Id != echo.Id {
+		return fmt.Errorf("membros receberam ids diferentes: %s e %s", echo.Id, delivered.Id)
+	}
+	r.state.messages = append(r.state.messages, echo)
+	return nil
+}
+
+func (r *Runner) testWSSubscribe(ctx context.Context) error {
+	if r.state.room == nil || r.state.tokenB == "" {
+		return ErrSkipped
+	}
+	wsA, err := r.dial(ctx, r.state.tokenA)
+	if err != nil {
+		return fmt.Errorf("conexão do remetente: %w", err)
+	}
+	defer wsA.Close()
+	wsB, err := r.dial(ctx, r.state.tokenB)
+	if err != nil {
+		return fmt.Errorf("conexão inscrita: %w", err)
+	}
+	defer wsB.Close()
+
+	// Inscrito em nenhuma sala, B só deveria receber contagens
+	subscribe, err := data.NewEnvelope(data.Subscribe{RoomIds: []string{}})
+	if err != nil {
+		return err
+	}
+	if err := r.sendFrame(wsB, subscribe); err != nil {
+		return err
+	}
+	// Os frames de uma conexão são processados em ordem: o eco confirma que o subscribe foi aplicado
+	own := r.chatFrame("conformance subscribe")
+	own.UserId = ""
+	if err := r.sendFrame(wsB, own); err != nil {
+		return err
+	}
+	echo, err := r.readEcho(ctx, wsB, own.Nonce)
+	if err != nil {
+		return fmt.Errorf("eco após subscribe: %w", err)
+	}
+	r.state.messages = append(r.state.messages, echo)
+
+	msg := r.chatFrame("conformance unread")
+	if err := r.sendFrame(wsA, msg); err != nil {
+		return err
+	}
+	echo, err = r.readEcho(ctx, wsA, msg.Nonce)
+	if err != nil {
+		return fmt.Errorf("remetente: %w", err)
+	}
+	r.state.messages = append(r.state.messages, echo)
+
+	deadline, _ := ctx.Deadline()
+	wsB.SetReadDeadline(deadline)
+	for {
+		_, raw, err := wsB.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("room.unread não recebido: %w", err)
+		}
+		if err := r.ws.Validate(ServerFrameSchema, raw); err != nil {
+			return fmt.Errorf("frame do servidor não segue o schema: %w", err)
+		}
+		switch ev := data.DecodeEvent(raw).(type) {
+		case data.MessageCreated:
+			if ev.Nonce == msg.Nonce {
+				return fmt.Errorf("%w: servidor sem suporte a subscribe", ErrSkipped)
+			}
+		case data.RoomUnread:
+			if ev.RoomId != r.state.room.Id {
+				continue
+			}
+			if ev.Unread < 1 {
+				return fmt.Errorf("room.unread com contagem %d", ev.Unread)
+			}
+			return nil
+		}
+	}
+}
+
+func (r *Runner) testWSPing(ctx context.Context) error {
+	if r.state.tokenA == "" {
+		return ErrSkipped
+	}
+	ws, err := r.dial(ctx, r.state.tokenA)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	pong := make(chan struct{}, 1)
+	ws.SetPongHandler(func(string) error {
+		pong <- struct{}{}
+		return nil
+	})
+	deadline, _ := ctx.Deadline()
+	if err := ws.WriteControl(websocket.PingMessage, []byte("conformance"), deadline); err != nil {
+		return err
+	}
+
+	// O pong só é processado durante a leitura
+	read := make(chan error, 1)
+	go func() {
+		ws.SetReadDeadline(deadline)
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				read <- err
+				return
+			}
+		}
+	}()
+	select {
+	case <-pong:
+		return nil
+	case err := <-read:
+		return fmt.Errorf("conexão encerrada antes do pong: %w", err)
+	case <-ctx.Done():
+		return errors.New("pong não recebido")
+	}
+}
+
+func (r *Runner) testHistoryPaging(ctx context.Context) error {
+	if len(r.state.messages) == 0 {
+		return ErrSkipped
+	}
+	ws, err := r.dial(ctx, r.state.tokenA)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+	for i := 1; i <= 3; i++ {
+		msg := r.chatFrame(fmt.Sprintf("conformance %d", i))
+		if err := r.sendFrame(ws, msg); err != nil {
+			return err
+		}
+		echo, err := r.readEcho(ctx, ws, msg.Nonce)
+		if err != nil {
+			return err
+		}
+		r.state.messages = append(r.state.messages, echo)
+	}
+	sent := r.state.messages
+
+	all, err := r.history(ctx, "", 0)
+	if err != nil {
+		return err
+	}
+	if err := sameIds(all, sent); err != nil {
+		return fmt.Errorf("histórico completo: %w", err)
+	}
+	latest, err := r.history(ctx, "", 2)
+	if err != nil {
+		return err
+	}
+	if err := sameIds(latest, sent[len(sent)-2:]); err != nil {
+		return fmt.Errorf("limit=2: %w", err)
+	}
+	older, err := r.history(ctx, latest[0].Id, 10)
+	if err != nil {
+		return err
+	}
+	if err := sameIds(older, sent[:len(sent)-2]); err != nil {
+		return fmt.Errorf("before=%s: %w", latest[0].Id, err)
+	}
+	return nil
+}
+
+// sameIds confere se as mensagens têm os ids esperados, na mesma ordem
+func sameIds(got, want []data.Message) error {
+	ids := func(ms []data.Message) []string {
+		out := make([]string, len(ms))
+		for i, m := range ms {
+			out[i] = m.Id
+		}
+		return out
+	}
+	if !slices.Equal(ids(got), ids(want)) {
+		return fmt.Errorf("ids %v, esperado %v", ids(got), ids(want))
+	}
+	return nil
+}
+
+func (r *Runner) testRefresh(ctx context.Context) error {
+	if r.state.tokenA == "" {
+		return ErrSkipped
+	}
+	status, raw, err := r.call(ctx, "POST", "/auth/refresh", r.state.tokenA, nil)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusNotFound || status == http.StatusMethodNotAllowed {
+		return fmt.Errorf("%w: servidor sem /auth/refresh", ErrSkipped)
+	}
+	if err := r.expect(status, raw, RefreshResponseSchema, http.StatusOK); err != nil {
+		return err
+	}
+	var res map[string]string
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return err
+	}
+	if _, err := r.roomsOf(ctx, res["token"]); err != nil {
+		return fmt.Errorf("novo token recusado: %w", err)
+	}
+	return nil
+}
+
+func (r *Runner) testLogout(ctx context.Context) error {
+	if r.state.tokenB == "" {
+		return ErrSkipped
+	}
+	status, raw, err := r.call(ctx, "POST", "/auth/logout", r.state.tokenB, nil)
+	if err != nil {
+		return err
+	}
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("status %d, esperado 2xx: %s", status, bytes.TrimSpace(raw))
+	}
+	status, raw, err = r.call(ctx, "GET", "/rooms", r.state.tokenB, nil)
+	if err != nil {
+		return err
+	}
+	if err := r.expect(status, raw, "", http.StatusUnauthorized, http.StatusForbidden); err != nil {
+		return fmt.Errorf("token continua válido após logout: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Package protocol publica o contrato entre o chatli e o servidor: a especificação OpenAPI
+// da API REST (openapi.json) e o JSON Schema dos frames do websocket (ws.schema.json).
+// Os documentos são embutidos no binário, conferidos contra os tipos do pacote data
+// (CheckTypes) e usados pela suíte de conformidade (Runner) para validar qualquer servidor.
+package protocol
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+//go:embed openapi.json
+var OpenAPI []byte
+
+//go:embed ws.schema.json
+var WSSchema []byte
+
+// Referências dos schemas usados pelo cliente
+const (
+	RoomSchema            = "#/components/schemas/Room"
+	MessageSchema         = "#/components/schemas/Message"
+	LoginResponseSchema   = "#/components/schemas/LoginResponse"
+	RefreshResponseSchema = "#/components/schemas/RefreshResponse"
+	ErrorSchema           = "#/components/schemas/Error"
+	ClientFrameSchema     = "#/$defs/ClientFrame"
+	ServerFrameSchema     = "#/$defs/ServerFrame"
+)
+
+// envelopeSchema retorna a referência do envelope de um tipo de evento, como
+// "#/$defs/MessageCreatedEnvelope" para "message.created"
+func envelopeSchema(eventType string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(eventType, func(r rune) bool { return r == '.' || r == '_' }) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return "#/$defs/" + b.String() + "Envelope"
+}
+
+// Document é um documento JSON (OpenAPI ou JSON Schema) cujos schemas podem ser
+// referenciados por JSON pointer, como "#/components/schemas/Room"
+type Document struct {
+	root any
+}
+
+// LoadOpenAPI carrega a especificação OpenAPI embutida
+func LoadOpenAPI() (*Document, error) {
+	return ParseDocument(OpenAPI)
+}
+
+// LoadWSSchema carrega o JSON Schema embutido dos frames do websocket
+func LoadWSSchema() (*Document, error) {
+	return ParseDocument(WSSchema)
+}
+
+// ParseDocument interpreta um documento JSON
+func ParseDocument(b []byte) (*Document, error) {
+	var root any
+	if err := json.Unmarshal(b, &root); err != nil {
+		return nil, fmt.Errorf("documento inválido: %w", err)
+	}
+	return &Document{root: root}, nil
+}
+
+// Schema retorna o schema apontado pela referência
+func (d *Document) Schema(ref string) (map[string]any, error) {
+	node, err := d.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	schema, ok := node.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s não é um schema", ref)
+	}
+	return schema, nil
+}
+
+// Validate confere o JSON bruto contra o schema apontado pela referência
+func (d *Document) Validate(ref string, raw []byte) error {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return fmt.Errorf("JSON inválido: %w", err)
+	}
+	return d.ValidateValue(ref, v)
+}
+
+// ValidateValue confere um valor já decodificado (com encoding/json) contra o schema
+func (d *Document) ValidateValue(ref string, v any) error {
+	schema, err := d.Schema(ref)
+	if err != nil {
+		return err
+	}
+	var errs validationErrors
+	d.validate(schema, v, "$", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
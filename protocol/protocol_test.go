@@ -0,0 +1,49 @@
+package protocol_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mellojp/chatli/protocol"
+	"github.com/mellojp/chatli/server"
+)
+
+func TestCheckTypes(t *testing.T) {
+	if err := protocol.CheckTypes(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestConformance roda a suíte de conformidade contra o servidor de referência
+func TestConformance(t *testing.T) {
+	srv := server.New(server.Options{})
+	defer srv.Close()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	r := &protocol.Runner{
+		BaseURL: ts.URL,
+		OnResult: func(res protocol.Result) {
+			switch {
+			case res.Skipped():
+				t.Logf("ignorado: %s: %v", res.Name, res.Err)
+			case !res.Passed():
+				t.Errorf("%s: %v", res.Name, res.Err)
+			}
+		},
+	}
+	report, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := report.Failed(); n > 0 {
+		t.Fatalf("%d de %d casos falharam", n, len(report.Results))
+	}
+	// O servidor de referência implementa todos os casos, inclusive os opcionais
+	for _, res := range report.Results {
+		if res.Skipped() {
+			t.Errorf("caso ignorado pelo servidor de referência: %s", res.Name)
+		}
+	}
+}
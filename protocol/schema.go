@@ -0,0 +1,194 @@
+package protocol
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// validationErrors reúne todas as violações encontradas num valor
+type validationErrors []string
+
+func (e validationErrors) Error() string {
+	return strings.Join(e, "; ")
+}
+
+func (e *validationErrors) add(path, format string, args ...any) {
+	*e = append(*e, path+": "+fmt.Sprintf(format, args...))
+}
+
+// resolve segue um JSON pointer local ("#/a/b")
+func (d *Document) resolve(ref string) (any, error) {
+	pointer, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil, fmt.Errorf("referência externa não suportada: %s", ref)
+	}
+	node := d.root
+	for _, part := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if part == "" {
+			continue
+		}
+		part = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+		obj, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("referência não encontrada: %s", ref)
+		}
+		if node, ok = obj[part]; !ok {
+			return nil, fmt.Errorf("referência não encontrada: %s", ref)
+		}
+	}
+	return node, nil
+}
+
+// validate implementa o subconjunto de JSON Schema usado pelos documentos do pacote:
+// $ref, type (inclusive em lista), nullable, enum, required, properties, items, allOf, oneOf,
+// minLength, minimum e format date-time
+func (d *Document) validate(schema map[string]any, v any, path string, errs *validationErrors) {
+	if ref, ok := schema["$ref"].(string); ok {
+		target, err := d.Schema(ref)
+		if err != nil {
+			errs.add(path, "%v", err)
+			return
+		}
+		d.validate(target, v, path, errs)
+		return
+	}
+
+	if v == nil && schema["nullable"] == true {
+		return
+	}
+	if types := schemaTypes(schema); len(types) > 0 && !matchesType(types, v) {
+		errs.add(path, "esperado %s, recebido %s", strings.Join(types, " ou "), jsonType(v))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !contains(enum, v) {
+		errs.add(path, "valor %v fora de %v", v, enum)
+	}
+
+	if allOf, ok := schema["allOf"].([]any); ok {
+		for _, sub := range allOf {
+			if s, ok := sub.(map[string]any); ok {
+				d.validate(s, v, path, errs)
+			}
+		}
+	}
+
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		matched := 0
+		for _, sub := range oneOf {
+			if s, ok := sub.(map[string]any); ok {
+				var subErrs validationErrors
+				d.validate(s, v, path, &subErrs)
+				if len(subErrs) == 0 {
+					matched++
+				}
+			}
+		}
+		if matched == 0 {
+			errs.add(path, "não corresponde a nenhum dos schemas de oneOf")
+		}
+	}
+
+	switch val := v.(type) {
+	case string:
+		if min, ok := schema["minLength"].(float64); ok && float64(len(val)) < min {
+			errs.add(path, "tamanho mínimo %v", min)
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, val); err != nil {
+				errs.add(path, "data inválida %q", val)
+			}
+		}
+	case float64:
+		if min, ok := schema["minimum"].(float64); ok && val < min {
+			errs.add(path, "mínimo %v", min)
+		}
+	case map[string]any:
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if _, ok := val[name.(string)]; !ok {
+					errs.add(path, "campo obrigatório ausente: %s", name)
+				}
+			}
+		}
+		if props, ok := schema["properties"].(map[string]any); ok {
+			for _, name := range slices.Sorted(maps.Keys(props)) {
+				sub := props[name]
+				field, ok := val[name]
+				if !ok {
+					continue
+				}
+				if s, ok := sub.(map[string]any); ok {
+					d.validate(s, field, path+"."+name, errs)
+				}
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range val {
+				d.validate(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	}
+}
+
+func schemaTypes(schema map[string]any) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var types []string
+		for _, s := range t {
+			if s, ok := s.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesType(types []string, v any) bool {
+	actual := jsonType(v)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType retorna o tipo JSON Schema de um valor decodificado por encoding/json
+func jsonType(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if val == math.Trunc(val) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func contains(values []any, v any) bool {
+	for _, e := range values {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
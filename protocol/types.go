@@ -0,0 +1,192 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/mellojp/chatli/data"
+)
+
+// typeBinding liga um schema ao tipo Go que o cliente usa para decodificá-lo
+type typeBinding struct {
+	load func() (*Document, error)
+	ref  string
+	typ  reflect.Type
+	// partial indica que o schema descreve só parte dos campos do tipo (ex.: frame enviado pelo cliente)
+	partial bool
+}
+
+var bindings = []typeBinding{
+	{LoadOpenAPI, RoomSchema, reflect.TypeFor[data.Room](), false},
+	{LoadOpenAPI, MessageSchema, reflect.TypeFor[data.Message](), false},
+	{LoadWSSchema, "#/$defs/Message", reflect.TypeFor[data.Message](), false},
+	{LoadWSSchema, "#/$defs/ChatFrame", reflect.TypeFor[data.Message](), true},
+	{LoadWSSchema, "#/$defs/MessageEdited", reflect.TypeFor[data.MessageEdited](), false},
+	{LoadWSSchema, "#/$defs/MessageDeleted", reflect.TypeFor[data.MessageDeleted](), false},
+	{LoadWSSchema, "#/$defs/Member", reflect.TypeFor[data.MemberJoined](), false},
+	{LoadWSSchema, "#/$defs/Member", reflect.TypeFor[data.MemberLeft](), false},
+	{LoadWSSchema, "#/$defs/Typing", reflect.TypeFor[data.Typing](), false},
+	{LoadWSSchema, "#/$defs/Presence", reflect.TypeFor[data.Presence](), false},
+	{LoadWSSchema, "#/$defs/RoomRenamed", reflect.TypeFor[data.RoomRenamed](), false},
+	{LoadWSSchema, "#/$defs/SystemNotice", reflect.TypeFor[data.SystemNotice](), false},
+	{LoadWSSchema, "#/$defs/RoomUnread", reflect.TypeFor[data.RoomUnread](), false},
+	{LoadWSSchema, "#/$defs/Subscription", reflect.TypeFor[data.Subscribe](), false},
+	{LoadWSSchema, "#/$defs/RoomList", reflect.TypeFor[data.Unsubscribe](), false},
+}
+
+// CheckTypes confere se os schemas publicados e os tipos do pacote data descrevem os
+// mesmos campos JSON, com tipos compatíveis. Deve passar sempre que um dos lados mudar.
+func CheckTypes() error {
+	var errs []error
+	for _, b := range bindings {
+		doc, err := b.load()
+		if err != nil {
+			return err
+		}
+		schema, err := doc.Schema(b.ref)
+		if err != nil {
+			return err
+		}
+		for _, problem := range compareType(schema, b.typ, b.partial) {
+			errs = append(errs, fmt.Errorf("%s x %s: %s", b.ref, b.typ, problem))
+		}
+	}
+	errs = append(errs, checkEnvelopes()...)
+	return errors.Join(errs...)
+}
+
+// checkEnvelopes confere se cada tipo de evento decodificado pelo pacote data tem um envelope
+// no schema e se ServerFrame ou ClientFrame o aceita
+func checkEnvelopes() []error {
+	doc, err := LoadWSSchema()
+	if err != nil {
+		return []error{err}
+	}
+	accepted := make(map[string]bool)
+	for _, ref := range []string{ServerFrameSchema, ClientFrameSchema} {
+		frame, err := doc.Schema(ref)
+		if err != nil {
+			return []error{err}
+		}
+		for _, alt := range frame["oneOf"].([]any) {
+			if ref, ok := alt.(map[string]any)["$ref"].(string); ok {
+				accepted[ref] = true
+			}
+		}
+	}
+
+	var errs []error
+	for _, eventType := range data.EventTypes() {
+		ref := envelopeSchema(eventType)
+		env, err := doc.Schema(ref)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("evento %s sem envelope: %w", eventType, err))
+			continue
+		}
+		if !accepted[ref] {
+			errs = append(errs, fmt.Errorf("evento %s fora de %s e %s", eventType, ServerFrameSchema, ClientFrameSchema))
+		}
+		props, _ := env["properties"].(map[string]any)
+		typ, _ := props["type"].(map[string]any)
+		if enum, _ := typ["enum"].([]any); len(enum) != 1 || enum[0] != eventType {
+			errs = append(errs, fmt.Errorf("%s: type deveria ser %q", ref, eventType))
+		}
+	}
+	return errs
+}
+
+// compareType lista as divergências entre as propriedades do schema e os campos do struct
+func compareType(schema map[string]any, typ reflect.Type, partial bool) []string {
+	props, _ := schema["properties"].(map[string]any)
+	var problems []string
+
+	fields := jsonFields(typ)
+	for name, field := range fields {
+		prop, ok := props[name].(map[string]any)
+		if !ok {
+			if !partial {
+				problems = append(problems, fmt.Sprintf("campo %s ausente no schema", name))
+			}
+			continue
+		}
+		if want := schemaTypeFor(field.Type); want != "" && !matchesSchemaType(prop, want) {
+			problems = append(problems, fmt.Sprintf("campo %s: Go %s, schema %v", name, field.Type, prop["type"]))
+		}
+		if field.Type == reflect.TypeFor[time.Time]() || field.Type == reflect.TypeFor[*time.Time]() {
+			if prop["format"] != "date-time" {
+				problems = append(problems, fmt.Sprintf("campo %s: esperado format date-time", name))
+			}
+		}
+		if field.Type.Kind() == reflect.Pointer && prop["nullable"] != true {
+			problems = append(problems, fmt.Sprintf("campo %s: ponteiro em Go, deveria ser nullable", name))
+		}
+	}
+	for name := range props {
+		if _, ok := fields[name]; !ok {
+			problems = append(problems, fmt.Sprintf("propriedade %s sem campo em Go", name))
+		}
+	}
+	return problems
+}
+
+// jsonFields mapeia os nomes JSON dos campos exportados do struct
+func jsonFields(typ reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+// schemaTypeFor retorna o tipo JSON Schema correspondente a um tipo Go
+func schemaTypeFor(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeFor[time.Time]() {
+		return "string"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	}
+	return ""
+}
+
+func matchesSchemaType(prop map[string]any, want string) bool {
+	types := schemaTypes(prop)
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		if t == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,227 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mellojp/chatli/data"
+)
+
+type ctxKey struct{}
+
+// authed exige um token válido no header Authorization (ou em ?token=, para clientes de websocket
+// que não enviam headers) e disponibiliza as claims no contexto da requisição
+func (s *Server) authed(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			token = r.URL.Query().Get("token")
+		}
+		c, err := s.verifyToken(token)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, "invalid or expired token")
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), ctxKey{}, c)))
+	}
+}
+
+func claimsFrom(r *http.Request) claims {
+	c, _ := r.Context().Value(ctxKey{}).(claims)
+	return c
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// decode lê o corpo JSON da requisição, respondendo 400 em caso de erro
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return false
+	}
+	return true
+}
+
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var req credentials
+	if !decode(w, r, &req) {
+		return
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" || req.Password == "" {
+		writeError(w, http.StatusBadRequest, "username and password are required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.users[req.Username]; exists {
+		writeError(w, http.StatusConflict, "username already taken")
+		return
+	}
+	u := &user{id: newID(), username: req.Username, salt: []byte(newID())}
+	u.hash = hashPassword(u.salt, req.Password)
+	s.users[u.username] = u
+
+	writeJSON(w, http.StatusCreated, map[string]string{"user_id": u.id, "username": u.username})
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var req credentials
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.RLock()
+	u, ok := s.users[strings.TrimSpace(req.Username)]
+	s.mu.RUnlock()
+	if !ok || !u.checkPassword(req.Password) {
+		writeError(w, http.StatusUnauthorized, "invalid username or password")
+		return
+	}
+
+	token, err := s.issueToken(u)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"token": token, "user_id": u.id})
+}
+
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	c := claimsFrom(r)
+	token, err := s.issueToken(&user{id: c.Subject, username: c.Username})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"token": token})
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	s.revoke(claimsFrom(r))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleRooms(w http.ResponseWriter, r *http.Request) {
+	userId := claimsFrom(r).Subject
+
+	s.mu.RLock()
+	rooms := []data.Room{}
+	for _, rm := range s.rooms {
+		if rm.members[userId] {
+			rooms = append(rooms, rm.Room)
+		}
+	}
+	s.mu.RUnlock()
+
+	sortRooms(rooms)
+	writeJSON(w, http.StatusOK, rooms)
+}
+
+func (s *Server) handleCreateRoom(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RoomName string `json:"room_name"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	req.RoomName = strings.TrimSpace(req.RoomName)
+	if req.RoomName == "" {
+		writeError(w, http.StatusBadRequest, "room_name is required")
+		return
+	}
+
+	userId := claimsFrom(r).Subject
+	rm := &room{
+		Room: data.Room{
+			Id:        newID(),
+			Name:      req.RoomName,
+			CreatorId: userId,
+			CreatedAt: s.now().UTC(),
+		},
+		// Quem cria a sala já entra nela
+		members: map[string]bool{userId: true},
+	}
+
+	s.mu.Lock()
+	s.rooms[rm.Id] = rm
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, rm.Room)
+}
+
+func (s *Server) handleJoinRoom(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RoomId string `json:"room_id"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	userId := claimsFrom(r).Subject
+	s.mu.Lock()
+	rm, ok := s.rooms[req.RoomId]
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, "room not found")
+		return
+	}
+	already := rm.members[userId]
+	rm.members[userId] = true
+	members := maps.Clone(rm.members)
+	s.mu.Unlock()
+
+	if already {
+		writeJSON(w, http.StatusOK, rm.Room)
+		return
+	}
+	c := claimsFrom(r)
+	s.hub.broadcast(data.MemberJoined{RoomId: rm.Id, UserId: c.Subject, Username: c.Username}, members, nil)
+	writeJSON(w, http.StatusCreated, rm.Room)
+}
+
+// handleHistory retorna, em ordem cronológica, até limit mensagens anteriores à mensagem
+// before (ou as mais recentes); sem limit, retorna todo o histórico
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := 0
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	messages, err := s.history(claimsFrom(r).Subject, query.Get("room_id"), query.Get("before"), limit)
+	switch {
+	case errors.Is(err, errRoomNotFound):
+		writeError(w, http.StatusNotFound, "room not found")
+	case errors.Is(err, errNotMember):
+		writeError(w, http.StatusForbidden, "not a member of this room")
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, messages)
+	}
+}
@@ -0,0 +1,379 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mellojp/chatli/data"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// clientQueue é quantas mensagens podem aguardar envio a um cliente lento antes de ele ser desconectado
+	clientQueue = 64
+	writeWait   = 10 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	// O servidor de referência aceita qualquer origem
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsClient é uma conexão de websocket de um usuário
+type wsClient struct {
+	claims claims
+	ws     *websocket.Conn
+	send   chan data.Envelope
+	done   chan struct{}
+	once   sync.Once
+
+	// filtered indica que a conexão já se inscreveu em salas e não recebe mais o fluxo global;
+	// subscribed são as salas acompanhadas. Ambos são protegidos por hub.mu.
+	filtered   bool
+	subscribed map[string]bool
+}
+
+// wants informa se a conexão recebe os eventos da sala; exige hub.mu travado
+func (c *wsClient) wants(roomId string) bool {
+	return roomId == "" || !c.filtered || c.subscribed[roomId]
+}
+
+func (c *wsClient) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.ws.Close()
+	})
+}
+
+// hub mantém as conexões abertas e distribui as mensagens aos membros de cada sala
+type hub struct {
+	srv *Server
+
+	mu      sync.Mutex
+	clients map[*wsClient]bool
+}
+
+func newHub(srv *Server) *hub {
+	return &hub{srv: srv, clients: make(map[*wsClient]bool)}
+}
+
+func (h *hub) handleWS(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &wsClient{
+		claims: claimsFrom(r),
+		ws:     ws,
+		send:   make(chan data.Envelope, clientQueue),
+		done:   make(chan struct{}),
+
+		subscribed: make(map[string]bool),
+	}
+
+	h.mu.Lock()
+	first := h.connections(c.claims.Subject) == 0
+	h.clients[c] = true
+	h.mu.Unlock()
+	if first {
+		h.announce(c.claims, data.PresenceOnline)
+	}
+
+	go h.writePump(c)
+	h.readPump(c)
+}
+
+// connections conta as conexões abertas do usuário; exige h.mu travado
+func (h *hub) connections(userId string) int {
+	n := 0
+	for c := range h.clients {
+		if c.claims.Subject == userId {
+			n++
+		}
+	}
+	return n
+}
+
+// announce avisa quem divide salas com o usuário que ele ficou online ou offline
+func (h *hub) announce(c claims, status string) {
+	ev := data.Presence{UserId: c.Subject, Username: c.Username, Status: status}
+	if status == data.PresenceOffline {
+		now := h.srv.now().UTC()
+		ev.LastSeen = &now
+	}
+	contacts := h.srv.contacts(c.Subject)
+	delete(contacts, c.Subject)
+	h.broadcast(ev, contacts, nil)
+}
+
+// readPump recebe as mensagens do cliente, grava e distribui; termina quando a conexão cai
+func (h *hub) readPump(c *wsClient) {
+	defer h.remove(c)
+
+	// Sem tráfego (nem pong) por dois intervalos de ping, o cliente é considerado morto
+	deadline := 2 * h.srv.opts.PingInterval
+	c.ws.SetReadDeadline(time.Now().Add(deadline))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(deadline))
+	})
+	pingHandler := c.ws.PingHandler()
+	c.ws.SetPingHandler(func(payload string) error {
+		c.ws.SetReadDeadline(time.Now().Add(deadline))
+		return pingHandler(payload)
+	})
+
+	for {
+		_, raw, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.ws.SetReadDeadline(time.Now().Add(deadline))
+
+		if err := h.receive(c, data.DecodeEvent(raw)); err != nil {
+			log.Printf("server: frame de %s descartado: %v", c.claims.Username, err)
+		}
+	}
+}
+
+// receive trata um frame do cliente: mensagens de chat (formato antigo ou message.created),
+// edição e remoção das próprias mensagens e avisos de digitação
+func (h *hub) receive(c *wsClient, ev data.Event) error {
+	switch ev := ev.(type) {
+	case data.MessageCreated:
+		stored, members, err := h.srv.post(c.claims, ev.Message)
+		if err != nil {
+			return err
+		}
+		h.deliver(stored, members, c)
+
+	case data.Subscribe:
+		h.subscribe(c, ev)
+
+	case data.Unsubscribe:
+		h.mu.Lock()
+		c.filtered = true
+		for _, id := range ev.RoomIds {
+			delete(c.subscribed, id)
+		}
+		h.mu.Unlock()
+
+	case data.MessageEdited:
+		edited, members, err := h.srv.edit(c.claims, ev)
+		if err != nil {
+			return err
+		}
+		h.broadcast(edited, members, nil)
+
+	case data.MessageDeleted:
+		members, err := h.srv.remove(c.claims, ev)
+		if err != nil {
+			return err
+		}
+		h.broadcast(ev, members, nil)
+
+	case data.Typing:
+		members, err := h.srv.members(c.claims.Subject, ev.RoomId)
+		if err != nil {
+			return err
+		}
+		ev.UserId, ev.Username = c.claims.Subject, c.claims.Username
+		// Quem digita não precisa do próprio aviso
+		h.broadcast(ev, members, c)
+
+	case data.UnknownEvent:
+		return fmt.Errorf("evento desconhecido %q: %v", ev.Type, ev.Err)
+
+	default:
+		return fmt.Errorf("evento %s não é aceito do cliente", ev.EventType())
+	}
+	return nil
+}
+
+// writePump escreve as mensagens da fila do cliente e envia pings periódicos
+func (h *hub) writePump(c *wsClient) {
+	ticker := time.NewTicker(h.srv.opts.PingInterval)
+	defer ticker.Stop()
+	defer c.close()
+
+	for {
+		select {
+		case env := <-c.send:
+			c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.ws.WriteJSON(env); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// subscribe inscreve a conexão nas salas das quais o usuário é membro. Na primeira inscrição,
+// a conexão recebe as não lidas das salas que não acompanha; as salas com horário de leitura
+// informado pelo cliente são recontadas a partir dele.
+func (h *hub) subscribe(c *wsClient, ev data.Subscribe) {
+	var allowed []string
+	for _, id := range ev.RoomIds {
+		if _, err := h.srv.members(c.claims.Subject, id); err == nil {
+			allowed = append(allowed, id)
+		}
+	}
+	h.srv.clearUnread(c.claims.Subject, allowed)
+
+	h.mu.Lock()
+	first := !c.filtered
+	c.filtered = true
+	for _, id := range allowed {
+		c.subscribed[id] = true
+	}
+	h.mu.Unlock()
+
+	if !first {
+		return
+	}
+	h.srv.unreadSince(c.claims, ev.Read, allowed)
+	for roomId, u := range h.srv.unreadOf(c.claims.Subject) {
+		_, read := ev.Read[roomId]
+		if (u.Unread > 0 || read) && !slices.Contains(allowed, roomId) {
+			h.send(c, u)
+		}
+	}
+}
+
+// deliver distribui uma mensagem nova: conexões inscritas na sala (ou no fluxo global) recebem
+// a mensagem; as demais, apenas a contagem de não lidas. O remetente sempre recebe o eco
+// (com o nonce original) para confirmar a entrega.
+func (h *hub) deliver(msg data.Message, members map[string]bool, sender *wsClient) {
+	// Quem acompanha a sala em alguma conexão já está lendo a mensagem
+	h.mu.Lock()
+	watching := map[string]bool{msg.UserId: true}
+	for c := range h.clients {
+		if c.filtered && c.subscribed[msg.RoomId] {
+			watching[c.claims.Subject] = true
+		}
+	}
+	h.mu.Unlock()
+	counts := h.srv.countUnread(msg, members, watching)
+
+	created, err := data.NewEnvelope(data.MessageCreated{Message: msg})
+	if err != nil {
+		log.Printf("server: falha ao codificar mensagem: %v", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		if !members[c.claims.Subject] {
+			continue
+		}
+		if c == sender || c.wants(msg.RoomId) {
+			h.push(c, created)
+			continue
+		}
+		if u, ok := counts[c.claims.Subject]; ok {
+			unread, err := data.NewEnvelope(u)
+			if err == nil {
+				h.push(c, unread)
+			}
+		}
+	}
+}
+
+// broadcast entrega o evento às conexões dos usuários informados, exceto a except.
+// Eventos de uma sala só chegam às conexões que a acompanham.
+func (h *hub) broadcast(ev data.Event, users map[string]bool, except *wsClient) {
+	env, err := data.NewEnvelope(ev)
+	if err != nil {
+		log.Printf("server: falha ao codificar %s: %v", ev.EventType(), err)
+		return
+	}
+	roomId := roomOf(ev)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		if c == except || !users[c.claims.Subject] || !c.wants(roomId) {
+			continue
+		}
+		h.push(c, env)
+	}
+}
+
+// send entrega um evento a uma única conexão
+func (h *hub) send(c *wsClient, ev data.Event) {
+	env, err := data.NewEnvelope(ev)
+	if err != nil {
+		log.Printf("server: falha ao codificar %s: %v", ev.EventType(), err)
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.push(c, env)
+}
+
+// push enfileira o frame para a conexão; exige hub.mu travado
+func (h *hub) push(c *wsClient, env data.Envelope) {
+	select {
+	case c.send <- env:
+	default:
+		// Cliente lento demais: desconecta em vez de travar a sala; readPump faz a limpeza
+		c.close()
+	}
+}
+
+// roomOf retorna a sala a que o evento pertence ("" para eventos do usuário, como presença)
+func roomOf(ev data.Event) string {
+	switch ev := ev.(type) {
+	case data.MessageCreated:
+		return ev.RoomId
+	case data.MessageEdited:
+		return ev.RoomId
+	case data.MessageDeleted:
+		return ev.RoomId
+	case data.MemberJoined:
+		return ev.RoomId
+	case data.MemberLeft:
+		return ev.RoomId
+	case data.Typing:
+		return ev.RoomId
+	case data.RoomRenamed:
+		return ev.RoomId
+	case data.SystemNotice:
+		return ev.RoomId
+	}
+	return ""
+}
+
+func (h *hub) remove(c *wsClient) {
+	h.mu.Lock()
+	_, open := h.clients[c]
+	delete(h.clients, c)
+	last := open && h.connections(c.claims.Subject) == 0
+	h.mu.Unlock()
+	c.close()
+	if last {
+		h.announce(c.claims, data.PresenceOffline)
+	}
+}
+
+func (h *hub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		c.ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, ""),
+			time.Now().Add(writeWait))
+		delete(h.clients, c)
+		c.close()
+	}
+}
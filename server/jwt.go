@@ -0,0 +1,67 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// errInvalidToken é retornado para tokens malformados, com assinatura inválida, expirados ou revogados
+var errInvalidToken = errors.New("token inválido")
+
+// jwtHeader é o cabeçalho fixo dos tokens emitidos (HS256)
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// claims são as informações carregadas no token
+type claims struct {
+	Subject  string `json:"sub"`
+	Username string `json:"username"`
+	IssuedAt int64  `json:"iat"`
+	Expiry   int64  `json:"exp"`
+	ID       string `json:"jti"`
+}
+
+// signToken emite um JWT HS256 com as claims informadas
+func signToken(secret []byte, c claims) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + signature(secret, unsigned), nil
+}
+
+// parseToken valida a assinatura e a expiração do token e retorna suas claims
+func parseToken(secret []byte, token string, now time.Time) (claims, error) {
+	var c claims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return c, errInvalidToken
+	}
+	expected := signature(secret, parts[0]+"."+parts[1])
+	if !hmac.Equal([]byte(parts[2]), []byte(expected)) {
+		return c, errInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return c, errInvalidToken
+	}
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return c, errInvalidToken
+	}
+	if c.Subject == "" || now.Unix() >= c.Expiry {
+		return c, errInvalidToken
+	}
+	return c, nil
+}
+
+func signature(secret []byte, unsigned string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mellojp/chatli/data"
+)
+
+var (
+	errRoomNotFound    = errors.New("sala não encontrada")
+	errNotMember       = errors.New("usuário não é membro da sala")
+	errUnknownId       = errors.New("mensagem before desconhecida")
+	errMessageNotFound = errors.New("mensagem não encontrada")
+	errNotAuthor       = errors.New("apenas o autor pode alterar a mensagem")
+)
+
+// member confere se o usuário participa da sala
+func (s *Server) member(userId, roomId string) error {
+	rm, ok := s.rooms[roomId]
+	if !ok {
+		return errRoomNotFound
+	}
+	if !rm.members[userId] {
+		return errNotMember
+	}
+	return nil
+}
+
+// history retorna uma cópia da página pedida do histórico da sala
+func (s *Server) history(userId, roomId, before string, limit int) ([]data.Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := s.member(userId, roomId); err != nil {
+		return nil, err
+	}
+
+	messages := s.rooms[roomId].messages
+	if before != "" {
+		i := slices.IndexFunc(messages, func(m data.Message) bool { return m.Id == before })
+		if i < 0 {
+			return nil, errUnknownId
+		}
+		messages = messages[:i]
+	}
+	if limit > 0 && len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+	return append([]data.Message{}, messages...), nil
+}
+
+// post grava a mensagem na sala, preenchendo id, autor e horário, e retorna os membros da sala
+func (s *Server) post(c claims, msg data.Message) (data.Message, map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.member(c.Subject, msg.RoomId); err != nil {
+		return msg, nil, err
+	}
+
+	rm := s.rooms[msg.RoomId]
+	msg.Id = strconv.Itoa(len(rm.messages)+1) + "-" + newID()
+	msg.Type = "chat"
+	msg.UserId = c.Subject
+	msg.SenderUsername = c.Username
+	msg.SentAt = s.now().UTC()
+	rm.messages = append(rm.messages, msg)
+	return msg, maps.Clone(rm.members), nil
+}
+
+// sortRooms ordena as salas por data de criação
+func sortRooms(rooms []data.Room) {
+	slices.SortFunc(rooms, func(a, b data.Room) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Id, b.Id)
+	})
+}
+
+// edit troca o conteúdo de uma mensagem do próprio autor
+func (s *Server) edit(c claims, ev data.MessageEdited) (data.MessageEdited, map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msg, members, err := s.authored(c, ev.RoomId, ev.Id)
+	if err != nil {
+		return ev, nil, err
+	}
+	ev.EditedAt = s.now().UTC()
+	msg.Content = ev.Content
+	msg.EditedAt = &ev.EditedAt
+	return ev, members, nil
+}
+
+// remove apaga uma mensagem do próprio autor
+func (s *Server) remove(c claims, ev data.MessageDeleted) (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, _, err := s.authored(c, ev.RoomId, ev.Id); err != nil {
+		return nil, err
+	}
+	rm := s.rooms[ev.RoomId]
+	rm.messages = slices.DeleteFunc(rm.messages, func(m data.Message) bool { return m.Id == ev.Id })
+	return maps.Clone(rm.members), nil
+}
+
+// authored localiza a mensagem e confere se o usuário é o autor; exige s.mu travado
+func (s *Server) authored(c claims, roomId, id string) (*data.Message, map[string]bool, error) {
+	if err := s.member(c.Subject, roomId); err != nil {
+		return nil, nil, err
+	}
+	rm := s.rooms[roomId]
+	i := slices.IndexFunc(rm.messages, func(m data.Message) bool { return m.Id == id })
+	if i < 0 {
+		return nil, nil, errMessageNotFound
+	}
+	if rm.messages[i].UserId != c.Subject {
+		return nil, nil, errNotAuthor
+	}
+	return &rm.messages[i], maps.Clone(rm.members), nil
+}
+
+// members retorna os membros da sala, se o usuário participar dela
+func (s *Server) members(userId, roomId string) (map[string]bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := s.member(userId, roomId); err != nil {
+		return nil, err
+	}
+	return maps.Clone(s.rooms[roomId].members), nil
+}
+
+// contacts retorna os usuários que dividem ao menos uma sala com o usuário
+func (s *Server) contacts(userId string) map[string]bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[string]bool)
+	for _, rm := range s.rooms {
+		if rm.members[userId] {
+			maps.Copy(out, rm.members)
+		}
+	}
+	return out
+}
+
+// countUnread soma a mensagem nova às não lidas dos membros da sala que não a estão
+// acompanhando e retorna as contagens atualizadas
+func (s *Server) countUnread(msg data.Message, members, skip map[string]bool) map[string]data.RoomUnread {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usernames := make(map[string]string)
+	for _, u := range s.users {
+		usernames[u.id] = u.username
+	}
+
+	counts := make(map[string]data.RoomUnread)
+	for userId := range members {
+		if skip[userId] {
+			continue
+		}
+		if s.unread[userId] == nil {
+			s.unread[userId] = make(map[string]data.RoomUnread)
+		}
+		u := s.unread[userId][msg.RoomId]
+		u.RoomId = msg.RoomId
+		u.Unread++
+		if msg.Mentions(usernames[userId]) {
+			u.Mentions++
+		}
+		u.LastMessageAt = msg.SentAt
+		s.unread[userId][msg.RoomId] = u
+		counts[userId] = u
+	}
+	return counts
+}
+
+// unreadSince recalcula as não lidas das salas do usuário a partir do horário da última
+// mensagem que ele leu em cada uma. Salas inscritas (skip) ou das quais ele não é membro
+// são ignoradas.
+func (s *Server) unreadSince(c claims, read map[string]time.Time, skip []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for roomId, at := range read {
+		if slices.Contains(skip, roomId) || s.member(c.Subject, roomId) != nil {
+			continue
+		}
+		u := data.RoomUnread{RoomId: roomId}
+		for _, msg := range s.rooms[roomId].messages {
+			u.LastMessageAt = msg.SentAt
+			if msg.UserId == c.Subject || !msg.SentAt.After(at) {
+				continue
+			}
+			u.Unread++
+			if msg.Mentions(c.Username) {
+				u.Mentions++
+			}
+		}
+		if s.unread[c.Subject] == nil {
+			s.unread[c.Subject] = make(map[string]data.RoomUnread)
+		}
+		s.unread[c.Subject][roomId] = u
+	}
+}
+
+// clearUnread zera as não lidas das salas que o usuário passou a acompanhar
+func (s *Server) clearUnread(userId string, roomIds []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range roomIds {
+		delete(s.unread[userId], id)
+	}
+}
+
+// unreadOf retorna as não lidas de cada sala do usuário
+func (s *Server) unreadOf(userId string) map[string]data.RoomUnread {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return maps.Clone(s.unread[userId])
+}
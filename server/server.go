@@ -0,0 +1,168 @@
+// Package server implementa em memória o backend esperado pelo chatli: autenticação com
+// JWT, salas, histórico paginado e o websocket que distribui as mensagens aos membros.
+// Serve para desenvolvimento local e testes de ponta a ponta:
+//
+//	srv := httptest.NewServer(server.New(server.Options{}))
+//	c := api.NewClient(srv.URL)
+//
+// Nada é persistido: o estado se perde quando o processo termina.
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mellojp/chatli/data"
+)
+
+// DefaultTokenTTL é a validade padrão dos tokens emitidos
+const DefaultTokenTTL = time.Hour
+
+// Options configura o Server
+type Options struct {
+	// Secret assina os tokens; vazio gera um segredo aleatório (tokens não sobrevivem a reinícios)
+	Secret []byte
+	// TokenTTL é a validade dos tokens (0 usa DefaultTokenTTL)
+	TokenTTL time.Duration
+	// PingInterval é o intervalo dos pings enviados aos clientes do websocket (0 usa 30s)
+	PingInterval time.Duration
+}
+
+type user struct {
+	id       string
+	username string
+	salt     []byte
+	hash     []byte
+}
+
+type room struct {
+	data.Room
+	members  map[string]bool
+	messages []data.Message
+}
+
+// Server é um http.Handler com todo o estado em memória
+type Server struct {
+	opts Options
+	mux  *http.ServeMux
+	hub  *hub
+	now  func() time.Time
+
+	mu      sync.RWMutex
+	users   map[string]*user // por username
+	rooms   map[string]*room // por id
+	revoked map[string]time.Time
+	// unread conta, por usuário e sala, as mensagens recebidas sem que ele acompanhasse a sala
+	unread map[string]map[string]data.RoomUnread
+}
+
+// New cria um Server vazio
+func New(opts Options) *Server {
+	if len(opts.Secret) == 0 {
+		opts.Secret = make([]byte, 32)
+		rand.Read(opts.Secret)
+	}
+	if opts.TokenTTL <= 0 {
+		opts.TokenTTL = DefaultTokenTTL
+	}
+	if opts.PingInterval <= 0 {
+		opts.PingInterval = 30 * time.Second
+	}
+
+	s := &Server{
+		opts:    opts,
+		mux:     http.NewServeMux(),
+		now:     time.Now,
+		users:   make(map[string]*user),
+		rooms:   make(map[string]*room),
+		revoked: make(map[string]time.Time),
+		unread:  make(map[string]map[string]data.RoomUnread),
+	}
+	s.hub = newHub(s)
+	s.routes()
+	return s
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("POST /auth/register", s.handleRegister)
+	s.mux.HandleFunc("POST /auth/login", s.handleLogin)
+	s.mux.HandleFunc("POST /auth/refresh", s.authed(s.handleRefresh))
+	s.mux.HandleFunc("POST /auth/logout", s.authed(s.handleLogout))
+	s.mux.HandleFunc("GET /rooms", s.authed(s.handleRooms))
+	s.mux.HandleFunc("POST /rooms/create", s.authed(s.handleCreateRoom))
+	s.mux.HandleFunc("POST /rooms/join", s.authed(s.handleJoinRoom))
+	s.mux.HandleFunc("GET /rooms/history", s.authed(s.handleHistory))
+	s.mux.HandleFunc("GET /ws", s.authed(s.hub.handleWS))
+}
+
+// ServeHTTP despacha a requisição para o endpoint correspondente
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close encerra as conexões de websocket abertas
+func (s *Server) Close() {
+	s.hub.closeAll()
+}
+
+// issueToken emite um token para o usuário
+func (s *Server) issueToken(u *user) (string, error) {
+	now := s.now()
+	return signToken(s.opts.Secret, claims{
+		Subject:  u.id,
+		Username: u.username,
+		IssuedAt: now.Unix(),
+		Expiry:   now.Add(s.opts.TokenTTL).Unix(),
+		ID:       newID(),
+	})
+}
+
+// verifyToken valida o token e confere se ele não foi revogado por um logout
+func (s *Server) verifyToken(token string) (claims, error) {
+	c, err := parseToken(s.opts.Secret, token, s.now())
+	if err != nil {
+		return c, err
+	}
+	s.mu.RLock()
+	_, revoked := s.revoked[c.ID]
+	s.mu.RUnlock()
+	if revoked {
+		return c, errInvalidToken
+	}
+	return c, nil
+}
+
+// revoke invalida o token até sua expiração, descartando revogações já vencidas
+func (s *Server) revoke(c claims) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for id, exp := range s.revoked {
+		if now.After(exp) {
+			delete(s.revoked, id)
+		}
+	}
+	s.revoked[c.ID] = time.Unix(c.Expiry, 0)
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func hashPassword(salt []byte, password string) []byte {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(password))
+	return h.Sum(nil)
+}
+
+func (u *user) checkPassword(password string) bool {
+	return subtle.ConstantTimeCompare(u.hash, hashPassword(u.salt, password)) == 1
+}
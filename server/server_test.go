@@ -0,0 +1,188 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mellojp/chatli/data"
+
+	"github.com/gorilla/websocket"
+)
+
+// testServer sobe o servidor em memória num httptest.Server
+func testServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := New(Options{})
+	ts := httptest.NewServer(srv)
+	t.Cleanup(func() {
+		ts.Close()
+		srv.Close()
+	})
+	return ts
+}
+
+// call faz uma requisição JSON e decodifica a resposta em out, conferindo o status
+func call(t *testing.T, ts *httptest.Server, method, path, token string, body, out any, status int) {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, ts.URL+path, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != status {
+		t.Fatalf("%s %s: status %d, esperado %d", method, path, resp.StatusCode, status)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+}
+
+// login cadastra o usuário e retorna o token e o id
+func login(t *testing.T, ts *httptest.Server, username string) (token, userId string) {
+	t.Helper()
+	creds := map[string]string{"username": username, "password": "segredo"}
+	call(t, ts, "POST", "/auth/register", "", creds, nil, http.StatusCreated)
+	var resp struct {
+		Token  string `json:"token"`
+		UserId string `json:"user_id"`
+	}
+	call(t, ts, "POST", "/auth/login", "", creds, &resp, http.StatusOK)
+	if resp.Token == "" || resp.UserId == "" {
+		t.Fatalf("login sem token ou user_id: %+v", resp)
+	}
+	return resp.Token, resp.UserId
+}
+
+func dial(t *testing.T, ts *httptest.Server, token string) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws"
+	ws, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Authorization": {"Bearer " + token}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return ws
+}
+
+// next lê o próximo evento do websocket
+func next(t *testing.T, ws *websocket.Conn) data.Event {
+	t.Helper()
+	_, raw, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data.DecodeEvent(raw)
+}
+
+func TestLoginCreateRoomAndEcho(t *testing.T) {
+	ts := testServer(t)
+	token, userId := login(t, ts, "alice")
+
+	var room data.Room
+	call(t, ts, "POST", "/rooms/create", token, map[string]string{"room_name": "geral"}, &room, http.StatusCreated)
+	if room.Id == "" || room.Name != "geral" || room.CreatorId != userId {
+		t.Fatalf("sala criada = %+v", room)
+	}
+	var rooms []data.Room
+	call(t, ts, "GET", "/rooms", token, nil, &rooms, http.StatusOK)
+	if len(rooms) != 1 || rooms[0].Id != room.Id {
+		t.Fatalf("salas = %+v", rooms)
+	}
+
+	ws := dial(t, ts, token)
+	sent := data.Message{RoomId: room.Id, Content: "olá", Nonce: "nonce-1"}
+	if err := ws.WriteJSON(sent); err != nil {
+		t.Fatal(err)
+	}
+	ev, ok := next(t, ws).(data.MessageCreated)
+	if !ok {
+		t.Fatalf("evento %T, esperado message.created", ev)
+	}
+	if ev.Nonce != sent.Nonce || ev.Content != sent.Content || ev.Id == "" || ev.UserId != userId || ev.SentAt.IsZero() {
+		t.Fatalf("eco = %+v", ev.Message)
+	}
+
+	var history []data.Message
+	call(t, ts, "GET", "/rooms/history?room_id="+room.Id, token, nil, &history, http.StatusOK)
+	if len(history) != 1 || history[0].Id != ev.Id {
+		t.Fatalf("histórico = %+v", history)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	ts := testServer(t)
+	login(t, ts, "alice")
+	call(t, ts, "POST", "/auth/login", "", map[string]string{"username": "alice", "password": "errada"}, nil, http.StatusUnauthorized)
+	call(t, ts, "GET", "/rooms", "token-invalido", nil, nil, http.StatusUnauthorized)
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws"
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("websocket sem token: %v", err)
+	}
+}
+
+func TestSubscribeCountsUnreadSinceReadMarker(t *testing.T) {
+	ts := testServer(t)
+	tokenA, _ := login(t, ts, "alice")
+	tokenB, _ := login(t, ts, "bob")
+
+	var room data.Room
+	call(t, ts, "POST", "/rooms/create", tokenA, map[string]string{"room_name": "geral"}, &room, http.StatusCreated)
+	call(t, ts, "POST", "/rooms/join", tokenB, map[string]string{"room_id": room.Id}, nil, http.StatusCreated)
+
+	wsA := dial(t, ts, tokenA)
+	var first data.Message
+	for i, content := range []string{"um", "dois @bob", "três"} {
+		if err := wsA.WriteJSON(data.Message{RoomId: room.Id, Content: content}); err != nil {
+			t.Fatal(err)
+		}
+		for {
+			if ev, ok := next(t, wsA).(data.MessageCreated); ok {
+				if i == 0 {
+					first = ev.Message
+				}
+				break
+			}
+		}
+	}
+
+	// Bob leu até a primeira mensagem: sobram duas, uma delas citando-o
+	wsB := dial(t, ts, tokenB)
+	env, err := data.NewEnvelope(data.Subscribe{RoomIds: []string{}, Read: map[string]time.Time{room.Id: first.SentAt}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wsB.WriteJSON(env); err != nil {
+		t.Fatal(err)
+	}
+	ev, ok := next(t, wsB).(data.RoomUnread)
+	if !ok {
+		t.Fatalf("evento %T, esperado room.unread", ev)
+	}
+	if ev.RoomId != room.Id || ev.Unread != 2 || ev.Mentions != 1 {
+		t.Fatalf("room.unread = %+v, esperado 2 não lidas e 1 menção", ev)
+	}
+}
@@ -0,0 +1,18 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// OpenLog abre (em modo append) o arquivo de log da interface, já que a TUI ocupa o terminal
+func OpenLog() (*os.File, error) {
+	path, err := Path("chatli.log")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+}
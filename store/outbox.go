@@ -0,0 +1,94 @@
+package store
+
+import (
+	"sync"
+
+	"github.com/mellojp/chatli/data"
+)
+
+// Outbox guarda as mensagens ainda não confirmadas pelo servidor, na ordem em que foram escritas
+type Outbox struct {
+	path string
+
+	mu    sync.Mutex
+	items []data.Message
+}
+
+// OpenOutbox carrega a outbox do usuário no perfil do disco (criando uma vazia se não existir)
+func OpenOutbox(profile, userId string) (*Outbox, error) {
+	path, err := Path(userFile("outbox", profile, userId, ".json"))
+	if err != nil {
+		return &Outbox{}, err
+	}
+	o := &Outbox{path: path}
+	if err := loadJSON(path, &o.items); err != nil {
+		return &Outbox{path: path}, err
+	}
+	return o, nil
+}
+
+// NewMemoryOutbox cria uma outbox que não é persistida
+func NewMemoryOutbox() *Outbox {
+	return &Outbox{}
+}
+
+// Add coloca a mensagem no fim da fila
+func (o *Outbox) Add(msg data.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.items = append(o.items, msg)
+	return o.save()
+}
+
+// Remove tira da fila a mensagem com o nonce informado e informa se ela existia
+func (o *Outbox) Remove(nonce string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i, msg := range o.items {
+		if msg.Nonce == nonce {
+			o.items = append(o.items[:i], o.items[i+1:]...)
+			o.save()
+			return true
+		}
+	}
+	return false
+}
+
+// Has informa se a mensagem com o nonce ainda aguarda confirmação
+func (o *Outbox) Has(nonce string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, msg := range o.items {
+		if msg.Nonce == nonce {
+			return true
+		}
+	}
+	return false
+}
+
+// All retorna uma cópia de todas as mensagens pendentes, em ordem
+func (o *Outbox) All() []data.Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]data.Message(nil), o.items...)
+}
+
+// Pending retorna as mensagens pendentes de uma sala, em ordem
+func (o *Outbox) Pending(roomId string) []data.Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var res []data.Message
+	for _, msg := range o.items {
+		if msg.RoomId == roomId {
+			res = append(res, msg)
+		}
+	}
+	return res
+}
+
+func (o *Outbox) save() error {
+	if o.path == "" {
+		return nil
+	}
+	return saveJSON(o.path, o.items)
+}
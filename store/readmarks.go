@@ -0,0 +1,113 @@
+package store
+
+import (
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/mellojp/chatli/data"
+)
+
+// ReadMarker aponta a última mensagem lida de uma sala
+type ReadMarker struct {
+	MessageId string    `json:"message_id"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// ReadMarkers guarda, por sala, até onde o usuário já leu. Mark altera apenas a memória;
+// Save grava no disco, para que quem marca a cada mensagem possa agrupar as escritas.
+type ReadMarkers struct {
+	path string
+	// saveMu serializa as gravações, que acontecem fora de mu
+	saveMu sync.Mutex
+
+	mu    sync.Mutex
+	marks map[string]ReadMarker
+	dirty bool
+}
+
+// OpenReadMarkers carrega os marcadores de leitura do usuário no perfil (vazios se não existirem)
+func OpenReadMarkers(profile, userId string) (*ReadMarkers, error) {
+	path, err := Path(userFile("read", profile, userId, ".json"))
+	if err != nil {
+		return NewMemoryReadMarkers(), err
+	}
+	r := &ReadMarkers{path: path, marks: make(map[string]ReadMarker)}
+	if err := loadJSON(path, &r.marks); err != nil {
+		return &ReadMarkers{path: path, marks: make(map[string]ReadMarker)}, err
+	}
+	if r.marks == nil {
+		r.marks = make(map[string]ReadMarker)
+	}
+	return r, nil
+}
+
+// NewMemoryReadMarkers cria marcadores que não são persistidos
+func NewMemoryReadMarkers() *ReadMarkers {
+	return &ReadMarkers{marks: make(map[string]ReadMarker)}
+}
+
+// Get retorna o marcador da sala; ok é false se ela nunca foi lida
+func (r *ReadMarkers) Get(roomId string) (marker ReadMarker, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	marker, ok = r.marks[roomId]
+	return marker, ok
+}
+
+// Times retorna o horário da última mensagem lida de cada sala com marcador
+func (r *ReadMarkers) Times() map[string]time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	times := make(map[string]time.Time, len(r.marks))
+	for roomId, marker := range r.marks {
+		times[roomId] = marker.SentAt
+	}
+	return times
+}
+
+// Mark registra a mensagem como lida na sala e informa se o marcador mudou. O marcador só
+// avança: mensagens anteriores à já marcada, ou ainda sem Id do servidor, são ignoradas.
+func (r *ReadMarkers) Mark(roomId string, msg data.Message) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if msg.Id == "" {
+		return false
+	}
+	if cur, ok := r.marks[roomId]; ok && (cur.MessageId == msg.Id || cur.SentAt.After(msg.SentAt)) {
+		return false
+	}
+	r.marks[roomId] = ReadMarker{MessageId: msg.Id, SentAt: msg.SentAt}
+	r.dirty = r.path != ""
+	return true
+}
+
+// Dirty informa se há marcadores ainda não gravados
+func (r *ReadMarkers) Dirty() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dirty
+}
+
+// Save grava os marcadores alterados desde a última gravação
+func (r *ReadMarkers) Save() error {
+	r.saveMu.Lock()
+	defer r.saveMu.Unlock()
+
+	r.mu.Lock()
+	if !r.dirty {
+		r.mu.Unlock()
+		return nil
+	}
+	marks := maps.Clone(r.marks)
+	r.dirty = false
+	r.mu.Unlock()
+
+	if err := saveJSON(r.path, marks); err != nil {
+		r.mu.Lock()
+		r.dirty = true
+		r.mu.Unlock()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mellojp/chatli/data"
+)
+
+func TestUserFileStaysInStateDir(t *testing.T) {
+	for _, userId := range []string{"42", "../../outro", "a/b"} {
+		name := userFile("read", "work", userId, ".json")
+		if filepath.Base(name) != name {
+			t.Errorf("userId %q: nome %q sai do diretório de estado", userId, name)
+		}
+	}
+}
+
+func TestReadMarkersSave(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	r, err := OpenReadMarkers("work", "../u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if !r.Mark("sala", data.Message{Id: "2", SentAt: at}) {
+		t.Fatal("Mark não avançou o marcador")
+	}
+	// O marcador só avança
+	if r.Mark("sala", data.Message{Id: "1", SentAt: at.Add(-time.Minute)}) || r.Mark("sala", data.Message{SentAt: at.Add(time.Minute)}) {
+		t.Error("Mark aceitou mensagem anterior ou sem Id")
+	}
+	// Mark não grava: a gravação fica para Save
+	if _, err := os.Stat(r.path); !os.IsNotExist(err) {
+		t.Fatalf("arquivo gravado antes de Save: %v", err)
+	}
+	if !r.Dirty() {
+		t.Fatal("marcador novo não ficou pendente")
+	}
+	if err := r.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Dirty() {
+		t.Error("marcador continua pendente após Save")
+	}
+
+	reopened, err := OpenReadMarkers("work", "../u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if marker, ok := reopened.Get("sala"); !ok || marker.MessageId != "2" || !marker.SentAt.Equal(at) {
+		t.Errorf("marcador reaberto = %+v, %v", marker, ok)
+	}
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/mellojp/chatli/data"
+)
+
+// SaveSession grava a sessão do perfil (incluindo o token JWT) com permissão 0600
+func SaveSession(profile string, s data.Session) error {
+	path, err := Path(profileFile("session", profile, ".json"))
+	if err != nil {
+		return err
+	}
+	return saveJSON(path, s)
+}
+
+// LoadSession lê a sessão salva do perfil; retorna nil se não houver nenhuma
+func LoadSession(profile string) (*data.Session, error) {
+	path, err := Path(profileFile("session", profile, ".json"))
+	if err != nil {
+		return nil, err
+	}
+	var s data.Session
+	if err := loadJSON(path, &s); err != nil {
+		return nil, err
+	}
+	if s.Token == "" {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+// DeleteSession apaga a sessão salva do perfil; não é erro se ela não existir
+func DeleteSession(profile string) error {
+	path, err := Path(profileFile("session", profile, ".json"))
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// Package store guarda em disco o estado local do chatli (outbox, sessão, marcadores de leitura)
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Dir retorna o diretório de estado do chatli, seguindo o XDG Base Directory
+// ($XDG_STATE_HOME/chatli ou ~/.local/state/chatli)
+func Dir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "chatli"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "chatli"), nil
+}
+
+// Path retorna o caminho de um arquivo dentro do diretório de estado
+func Path(name string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// profileFile monta o nome de um arquivo separado por perfil; o perfil anônimo
+// (sem arquivo de configuração) usa o nome sem sufixo
+func profileFile(base, profile, ext string) string {
+	if profile == "" {
+		return base + ext
+	}
+	return base + "-" + safeName(profile) + ext
+}
+
+// userFile monta o nome de um arquivo separado por perfil e usuário
+func userFile(base, profile, userId, ext string) string {
+	return profileFile(base, profile, "-"+safeName(userId)+ext)
+}
+
+// safeName torna um nome vindo da configuração ou do servidor seguro para compor um nome
+// de arquivo: nomes simples são mantidos; os demais (com "/", "..", etc.) viram um hash,
+// para que nunca apontem para fora do diretório de estado
+func safeName(name string) string {
+	simple := name != "" && name[0] != '.'
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			simple = false
+			break
+		}
+	}
+	if simple {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	return "h" + hex.EncodeToString(sum[:8])
+}
+
+// loadJSON lê o arquivo para v; um arquivo inexistente não é erro e deixa v intacto
+func loadJSON(path string, v any) error {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// saveJSON grava v de forma atômica (arquivo temporário + rename) com permissão 0600
+func saveJSON(path string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
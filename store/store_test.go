@@ -0,0 +1,44 @@
+package store
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSafeName(t *testing.T) {
+	for _, name := range []string{"work", "casa-2", "my_profile", "v1.2", "ABC"} {
+		if got := safeName(name); got != name {
+			t.Errorf("safeName(%q) = %q, esperado o próprio nome", name, got)
+		}
+	}
+	for _, name := range []string{"", ".", "..", ".oculto", "../../etc/passwd", "a/b", `a\b`, "com espaço", "ação"} {
+		got := safeName(name)
+		if got == name || !strings.HasPrefix(got, "h") || strings.ContainsAny(got, `/\.`) {
+			t.Errorf("safeName(%q) = %q, esperado um hash", name, got)
+		}
+		if again := safeName(name); again != got {
+			t.Errorf("safeName(%q) não é estável: %q e %q", name, got, again)
+		}
+	}
+	if safeName("a/b") == safeName("a\\b") {
+		t.Error("nomes diferentes geraram o mesmo hash")
+	}
+}
+
+func TestProfileFileStaysInStateDir(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir, err := Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, profile := range []string{"", "work", "../../fora", "/abs/olute"} {
+		path, err := Path(profileFile("session", profile, ".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Dir(path) != dir {
+			t.Errorf("perfil %q: arquivo %s fora de %s", profile, path, dir)
+		}
+	}
+}
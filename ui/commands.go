@@ -0,0 +1,167 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/mellojp/chatli/api"
+	"github.com/mellojp/chatli/data"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// loginResultMsg é o resultado do login completo (sessão, salas e websocket)
+type loginResultMsg struct {
+	Req     uint64
+	Session data.Session
+	Conn    *api.Conn
+	Err     error
+}
+
+// historyLoadedMsg traz o histórico carregado de uma sala
+type historyLoadedMsg struct {
+	Req      uint64
+	RoomId   string
+	Messages []data.Message
+	Err      error
+}
+
+// registerResultMsg indica o fim do cadastro de um usuário
+type registerResultMsg struct {
+	Req uint64
+	Err error
+}
+
+// roomCreatedMsg traz a sala recém-criada
+type roomCreatedMsg struct {
+	Req  uint64
+	Room *data.Room
+	Err  error
+}
+
+// roomJoinedMsg traz a lista de salas atualizada após entrar em uma sala
+type roomJoinedMsg struct {
+	Req    uint64
+	RoomId string
+	Rooms  []data.Room
+	Err    error
+}
+
+// startRequest cria o contexto da operação em andamento, cancelando a anterior se houver.
+// O número retornado acompanha o resultado, para que o de uma operação substituída seja descartado.
+func (m *Model) startRequest() (context.Context, uint64) {
+	m.cancelRequest()
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.request++
+	return ctx, m.request
+}
+
+// pending informa se há uma requisição em andamento
+func (m *Model) pending() bool {
+	return m.cancel != nil
+}
+
+// finishRequest encerra a requisição pendente e informa se o resultado ainda deve ser aplicado.
+// Resultados de requisições canceladas pelo usuário ou já substituídas por outra são descartados.
+func (m *Model) finishRequest(req uint64, err error) bool {
+	if m.cancel == nil || req != m.request || errors.Is(err, context.Canceled) {
+		return false
+	}
+	m.cancelRequest()
+	return true
+}
+
+// cancelRequest aborta a operação em andamento (ex.: usuário pressionou esc)
+func (m *Model) cancelRequest() {
+	if m.cancel != nil {
+		m.cancel()
+		m.cancel = nil
+	}
+}
+
+// client retorna uma cópia do Client do perfil com a sessão atual, para uso exclusivo de um comando
+func (m *Model) client() *api.Client {
+	c := *m.Client
+	c.Session = m.Session
+	return &c
+}
+
+func loginCmd(ctx context.Context, req uint64, c *api.Client, username, password string) tea.Cmd {
+	return func() tea.Msg {
+		session, err := c.LoginContext(ctx, username, password)
+		if err != nil {
+			return loginResultMsg{Req: req, Err: err}
+		}
+
+		// Carrega as salas do usuário do servidor
+		rooms, err := c.GetUserRoomsContext(ctx)
+		if err == nil {
+			session.JoinedRooms = rooms
+		}
+
+		// Conecta WebSocket Global
+		conn, err := c.Connect(ctx)
+		if err != nil {
+			return loginResultMsg{Req: req, Session: *session, Err: err}
+		}
+		return loginResultMsg{Req: req, Session: *session, Conn: conn}
+	}
+}
+
+func registerCmd(ctx context.Context, req uint64, c *api.Client, username, password string) tea.Cmd {
+	return func() tea.Msg {
+		return registerResultMsg{Req: req, Err: c.RegisterContext(ctx, username, password)}
+	}
+}
+
+func createRoomCmd(ctx context.Context, req uint64, c *api.Client, roomName string) tea.Cmd {
+	return func() tea.Msg {
+		room, err := c.CreateRoomContext(ctx, roomName)
+		return roomCreatedMsg{Req: req, Room: room, Err: err}
+	}
+}
+
+func joinRoomCmd(ctx context.Context, req uint64, c *api.Client, roomId string) tea.Cmd {
+	return func() tea.Msg {
+		if err := c.JoinRoomContext(ctx, roomId); err != nil {
+			return roomJoinedMsg{Req: req, RoomId: roomId, Err: err}
+		}
+
+		// Atualiza lista de salas para pegar o nome
+		rooms, err := c.GetUserRoomsContext(ctx)
+		if err != nil {
+			rooms = c.Session.JoinedRooms
+		}
+		return roomJoinedMsg{Req: req, RoomId: roomId, Rooms: rooms}
+	}
+}
+
+func loadHistoryCmd(ctx context.Context, req uint64, c *api.Client, roomId string) tea.Cmd {
+	return func() tea.Msg {
+		messages, err := c.LoadChatMessagesPageContext(ctx, roomId, "", historyPageSize)
+		return historyLoadedMsg{Req: req, RoomId: roomId, Messages: messages, Err: err}
+	}
+}
+
+// describeError traduz erros da API em mensagens legíveis para o usuário
+func describeError(err error) string {
+	var apiErr *api.Error
+	if !errors.As(err, &apiErr) {
+		return err.Error()
+	}
+	switch {
+	case errors.Is(err, api.ErrConflict) && apiErr.Op == "/auth/register":
+		return "username already taken"
+	case (errors.Is(err, api.ErrUnauthorized) || errors.Is(err, api.ErrForbidden)) && apiErr.Op == "/auth/login":
+		return "invalid username or password"
+	case errors.Is(err, api.ErrUnauthorized):
+		return "session expired, please log in again"
+	case errors.Is(err, api.ErrNotFound) && strings.HasPrefix(apiErr.Op, "/rooms"):
+		return "room does not exist"
+	case apiErr.Message != "":
+		return apiErr.Message
+	}
+	return err.Error()
+}
@@ -0,0 +1,187 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mellojp/chatli/api"
+	"github.com/mellojp/chatli/data"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// highLatency é a partir de quando o indicador de lag fica amarelo
+const highLatency = 500 * time.Millisecond
+
+type connState int
+
+const (
+	connOffline connState = iota
+	connOnline
+	connReconnecting
+)
+
+// reconnectMsg dispara uma nova tentativa de conexão após o backoff
+type reconnectMsg struct{}
+
+// reconnectResultMsg traz o resultado de uma tentativa de reconexão
+type reconnectResultMsg struct {
+	Conn *api.Conn
+	Err  error
+}
+
+// latencyMsg traz a latência medida pelo heartbeat da conexão
+type latencyMsg struct {
+	Conn    *api.Conn
+	Latency time.Duration
+}
+
+// backfillMsg traz o histórico recarregado de uma sala após a reconexão
+type backfillMsg struct {
+	Session  uint64
+	RoomId   string
+	Messages []data.Message
+	Err      error
+}
+
+func scheduleReconnect(delay time.Duration) tea.Cmd {
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		return reconnectMsg{}
+	})
+}
+
+func reconnectCmd(c *api.Client) tea.Cmd {
+	return func() tea.Msg {
+		conn, err := c.Connect(context.Background())
+		return reconnectResultMsg{Conn: conn, Err: err}
+	}
+}
+
+func waitForLatency(conn *api.Conn) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case d := <-conn.Latency():
+			return latencyMsg{Conn: conn, Latency: d}
+		case <-conn.Done():
+			return nil
+		}
+	}
+}
+
+// startConnection passa a usar a conexão informada, acompanhando suas mensagens e latência
+func (m *Model) startConnection(conn *api.Conn) tea.Cmd {
+	m.WSConn = conn
+	m.ConnState = connOnline
+	m.Latency = 0
+	m.backoff.Reset()
+	// Um flush em andamento na conexão anterior vai falhar; a nova conexão recomeça a fila
+	m.flushing = false
+	return tea.Batch(WaitForEvent(conn), waitForLatency(conn), m.subscribeCurrent(), m.checkOutbox(), m.flushOutbox())
+}
+
+func backfillCmd(session uint64, c *api.Client, roomId string, last *data.Message) tea.Cmd {
+	return func() tea.Msg {
+		messages, err := loadSince(c, roomId, last)
+		return backfillMsg{Session: session, RoomId: roomId, Messages: messages, Err: err}
+	}
+}
+
+// lastMessage retorna a mensagem mais recente já carregada da sala (nil se nenhuma)
+func (m *Model) lastMessage(roomId string) *data.Message {
+	msgs := m.ChatsHistory[roomId].Messages()
+	if len(msgs) == 0 {
+		return nil
+	}
+	return &msgs[len(msgs)-1]
+}
+
+// handleConnection trata os eventos do supervisor da conexão websocket
+func (m *Model) handleConnection(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case SocketError:
+		// Erros de conexões antigas (já substituídas ou encerradas) são ignorados
+		if msg.Conn == nil || msg.Conn != m.WSConn {
+			return nil
+		}
+		m.WSConn.Close()
+		m.WSConn = nil
+		m.ConnState = connReconnecting
+		return scheduleReconnect(m.backoff.Next())
+
+	case reconnectMsg:
+		if m.ConnState != connReconnecting {
+			return nil
+		}
+		return reconnectCmd(m.client())
+
+	case reconnectResultMsg:
+		if m.ConnState != connReconnecting {
+			if msg.Conn != nil {
+				msg.Conn.Close()
+			}
+			return nil
+		}
+		if msg.Err != nil {
+			// Token recusado no handshake: tentar de novo não adianta
+			if cmd, ok := m.handleUnauthorized(msg.Err); ok {
+				return cmd
+			}
+			return scheduleReconnect(m.backoff.Next())
+		}
+		// Recarrega o histórico da sala aberta para recuperar o que chegou durante a queda;
+		// as demais são recarregadas ao entrar
+		cmds := []tea.Cmd{m.startConnection(msg.Conn)}
+		if m.State == chatView && m.CurrentRoom != "" {
+			cmds = append(cmds, backfillCmd(m.session, m.client(), m.CurrentRoom, m.lastMessage(m.CurrentRoom)))
+		}
+		return tea.Batch(cmds...)
+
+	case latencyMsg:
+		// Medições de conexões antigas são descartadas
+		if msg.Conn != m.WSConn {
+			return nil
+		}
+		m.Latency = msg.Latency
+		return waitForLatency(m.WSConn)
+
+	case backfillMsg:
+		// Resultados que chegam depois do logout (ou do login de outro usuário) não devem
+		// recriar o histórico
+		if msg.Err != nil || msg.Session != m.session {
+			return nil
+		}
+		// Salas fechadas durante a busca tiveram o histórico descartado e são recarregadas ao voltar
+		history, ok := m.ChatsHistory[msg.RoomId]
+		if !ok || !m.viewing(msg.RoomId) {
+			return nil
+		}
+		history.Merge(msg.Messages)
+		cmd := tea.Batch(m.reconcileOutbox(msg.RoomId, msg.Messages), m.markRead(msg.RoomId))
+		atBottom := m.Viewport.AtBottom()
+		m.Viewport.SetContent(RenderChatView(m))
+		if atBottom {
+			m.Viewport.GotoBottom()
+		}
+		return cmd
+	}
+	return nil
+}
+
+// renderConnState descreve o estado da conexão para o cabeçalho do chat
+func renderConnState(m *Model) string {
+	switch m.ConnState {
+	case connOnline:
+		if m.Latency == 0 {
+			return SuccessStyle.Render("● online")
+		}
+		lag := fmt.Sprintf("● online %dms", m.Latency.Milliseconds())
+		if m.Latency > highLatency {
+			return WarningStyle.Render(lag)
+		}
+		return SuccessStyle.Render(lag)
+	case connReconnecting:
+		return ErrorStyle.Render(fmt.Sprintf("○ reconnecting (attempt %d)…", m.backoff.Attempt()))
+	}
+	return HelpStyle.Render("○ offline")
+}
@@ -0,0 +1,261 @@
+package ui
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mellojp/chatli/api"
+	"github.com/mellojp/chatli/data"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// typingTimeout é quanto tempo o aviso "digitando" dura sem um novo evento
+const typingTimeout = 6 * time.Second
+
+// eventSource identifica a conexão que entregou o evento, para descartar eventos de conexões antigas
+type eventSource struct {
+	Conn *api.Conn
+}
+
+func (e eventSource) source() *api.Conn { return e.Conn }
+
+// Cada evento do websocket chega ao Update como um tea.Msg próprio
+type (
+	messageCreatedMsg struct {
+		eventSource
+		data.MessageCreated
+	}
+	messageEditedMsg struct {
+		eventSource
+		data.MessageEdited
+	}
+	messageDeletedMsg struct {
+		eventSource
+		data.MessageDeleted
+	}
+	memberJoinedMsg struct {
+		eventSource
+		data.MemberJoined
+	}
+	memberLeftMsg struct {
+		eventSource
+		data.MemberLeft
+	}
+	typingMsg struct {
+		eventSource
+		data.Typing
+	}
+	presenceMsg struct {
+		eventSource
+		data.Presence
+	}
+	roomRenamedMsg struct {
+		eventSource
+		data.RoomRenamed
+	}
+	systemNoticeMsg struct {
+		eventSource
+		data.SystemNotice
+	}
+	roomUnreadMsg struct {
+		eventSource
+		data.RoomUnread
+	}
+	unknownEventMsg struct {
+		eventSource
+		data.UnknownEvent
+	}
+)
+
+// typingExpiredMsg pede um novo render para apagar avisos de digitação vencidos
+type typingExpiredMsg struct{}
+
+// WaitForEvent aguarda o próximo evento da conexão e o converte no tea.Msg correspondente
+func WaitForEvent(conn *api.Conn) tea.Cmd {
+	return func() tea.Msg {
+		ev, ok := <-conn.Events()
+		if !ok {
+			return SocketError{Conn: conn, Err: conn.Err()}
+		}
+		src := eventSource{Conn: conn}
+		switch ev := ev.(type) {
+		case data.MessageCreated:
+			return messageCreatedMsg{src, ev}
+		case data.MessageEdited:
+			return messageEditedMsg{src, ev}
+		case data.MessageDeleted:
+			return messageDeletedMsg{src, ev}
+		case data.MemberJoined:
+			return memberJoinedMsg{src, ev}
+		case data.MemberLeft:
+			return memberLeftMsg{src, ev}
+		case data.Typing:
+			return typingMsg{src, ev}
+		case data.Presence:
+			return presenceMsg{src, ev}
+		case data.RoomRenamed:
+			return roomRenamedMsg{src, ev}
+		case data.SystemNotice:
+			return systemNoticeMsg{src, ev}
+		case data.RoomUnread:
+			return roomUnreadMsg{src, ev}
+		case data.UnknownEvent:
+			return unknownEventMsg{src, ev}
+		}
+		return unknownEventMsg{src, data.UnknownEvent{Type: ev.EventType()}}
+	}
+}
+
+// handleEvent aplica um evento do websocket e volta a aguardar o próximo
+func (m *Model) handleEvent(msg interface{ source() *api.Conn }) tea.Cmd {
+	// Eventos de conexões já encerradas (logout ou reconexão) são descartados
+	if msg.source() != m.WSConn {
+		return nil
+	}
+	cmds := []tea.Cmd{WaitForEvent(m.WSConn)}
+
+	switch msg := msg.(type) {
+	case messageCreatedMsg:
+		cmds = append(cmds, m.receiveMessage(msg.Message))
+		// Quem enviou a mensagem parou de digitar
+		m.setTyping(msg.RoomId, msg.SenderUsername, false)
+
+	case messageEditedMsg:
+		m.roomHistory(msg.RoomId).Edit(msg.Id, msg.Content, msg.EditedAt)
+		m.refreshRoom(msg.RoomId)
+
+	case messageDeletedMsg:
+		m.roomHistory(msg.RoomId).Remove(msg.Id)
+		m.refreshRoom(msg.RoomId)
+
+	case memberJoinedMsg:
+		m.addNotice(msg.RoomId, msg.Username+" entrou na sala")
+
+	case memberLeftMsg:
+		m.addNotice(msg.RoomId, msg.Username+" saiu da sala")
+		m.setTyping(msg.RoomId, msg.Username, false)
+
+	case typingMsg:
+		if msg.UserId == m.Session.UserId {
+			break
+		}
+		m.setTyping(msg.RoomId, msg.Username, msg.Typing.Typing)
+		if msg.Typing.Typing {
+			cmds = append(cmds, tea.Tick(typingTimeout, func(time.Time) tea.Msg { return typingExpiredMsg{} }))
+		}
+
+	case presenceMsg:
+		m.presence[msg.UserId] = msg.Status
+		m.refreshRoom(m.CurrentRoom)
+
+	case roomRenamedMsg:
+		for i, r := range m.Session.JoinedRooms {
+			if r.Id == msg.RoomId {
+				m.addNotice(msg.RoomId, "sala renomeada de "+r.Name+" para "+msg.Name)
+				m.Session.JoinedRooms[i].Name = msg.Name
+			}
+		}
+
+	case systemNoticeMsg:
+		if msg.RoomId != "" {
+			m.addNotice(msg.RoomId, msg.Text)
+			break
+		}
+		switch msg.Level {
+		case "error":
+			m.ErrorMsg = msg.Text
+		case "warning":
+			m.WarningMsg = msg.Text
+		default:
+			m.SuccessMsg = msg.Text
+		}
+
+	case roomUnreadMsg:
+		m.unreadPushed = true
+		// Na sala aberta, a contagem indica mensagens enviadas antes de a inscrição valer:
+		// elas são buscadas no histórico
+		if m.viewing(msg.RoomId) {
+			if msg.Unread == 0 {
+				break
+			}
+			cmds = append(cmds, backfillCmd(m.session, m.client(), msg.RoomId, m.lastMessage(msg.RoomId)))
+			break
+		}
+		m.setUnread(msg.RoomUnread)
+
+	case unknownEventMsg:
+		// Servidores mais novos podem mandar eventos que este cliente ainda não conhece
+		if msg.Err != nil {
+			log.Printf("websocket: frame inválido (%s): %v: %s", msg.Type, msg.Err, msg.Raw)
+		} else {
+			log.Printf("websocket: evento desconhecido %q ignorado: %s", msg.Type, msg.Raw)
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// addNotice registra uma linha de sistema na sala, exibida entre as mensagens pela ordem de chegada
+func (m *Model) addNotice(roomId, text string) {
+	m.notices[roomId] = append(m.notices[roomId], data.Message{
+		Type:    "system",
+		Content: text,
+		RoomId:  roomId,
+		SentAt:  time.Now(),
+	})
+	m.refreshRoom(roomId)
+}
+
+// setTyping marca ou desmarca o usuário como digitando na sala
+func (m *Model) setTyping(roomId, username string, typing bool) {
+	if username == "" {
+		return
+	}
+	if !typing {
+		delete(m.typing[roomId], username)
+		return
+	}
+	if m.typing[roomId] == nil {
+		m.typing[roomId] = make(map[string]time.Time)
+	}
+	m.typing[roomId][username] = time.Now().Add(typingTimeout)
+}
+
+// typingUsers retorna, em ordem alfabética, quem está digitando na sala
+func (m *Model) typingUsers(roomId string) []string {
+	var users []string
+	now := time.Now()
+	for name, until := range m.typing[roomId] {
+		if now.Before(until) {
+			users = append(users, name)
+		}
+	}
+	sort.Strings(users)
+	return users
+}
+
+// renderTyping descreve quem está digitando na sala atual
+func renderTyping(m *Model) string {
+	users := m.typingUsers(m.CurrentRoom)
+	switch len(users) {
+	case 0:
+		return ""
+	case 1:
+		return users[0] + " is typing..."
+	}
+	return strings.Join(users, ", ") + " are typing..."
+}
+
+// refreshRoom redesenha o histórico se a sala estiver aberta, mantendo a posição de leitura
+func (m *Model) refreshRoom(roomId string) {
+	if m.State != chatView || m.CurrentRoom != roomId {
+		return
+	}
+	atBottom := m.Viewport.AtBottom()
+	m.Viewport.SetContent(RenderChatView(m))
+	if atBottom {
+		m.Viewport.GotoBottom()
+	}
+}
@@ -0,0 +1,176 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mellojp/chatli/api"
+	"github.com/mellojp/chatli/data"
+	"github.com/mellojp/chatli/store"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// tokenWarnBefore é com quanto tempo de antecedência a expiração do token é tratada
+const tokenWarnBefore = 2 * time.Minute
+
+// tokenExpiringMsg avisa que o token está perto de expirar
+type tokenExpiringMsg struct {
+	Token string
+}
+
+// tokenExpiredMsg avisa que o token expirou
+type tokenExpiredMsg struct {
+	Token string
+}
+
+// tokenRefreshedMsg traz o resultado da renovação do token. Rejected indica que a renovação
+// foi pedida porque o servidor recusou o token: se ela falhar, a sessão já está morta.
+type tokenRefreshedMsg struct {
+	OldToken string
+	Rejected bool
+	Session  *data.Session
+	Err      error
+}
+
+func refreshCmd(c *api.Client, rejected bool) tea.Cmd {
+	oldToken := c.Session.Token
+	return func() tea.Msg {
+		session, err := c.RefreshContext(context.Background())
+		return tokenRefreshedMsg{OldToken: oldToken, Rejected: rejected, Session: session, Err: err}
+	}
+}
+
+// scheduleExpiry lê o exp do token da sessão e agenda o tratamento da expiração.
+// Tokens sem exp (ou que não são JWT) não são acompanhados.
+func (m *Model) scheduleExpiry() tea.Cmd {
+	exp, err := api.TokenExpiry(m.Session.Token)
+	if err != nil {
+		m.TokenExpiry = time.Time{}
+		return nil
+	}
+	m.TokenExpiry = exp
+
+	token := m.Session.Token
+	wait := time.Until(exp) - tokenWarnBefore
+	if wait < 0 {
+		wait = 0
+	}
+	return tea.Tick(wait, func(time.Time) tea.Msg {
+		return tokenExpiringMsg{Token: token}
+	})
+}
+
+// handleExpiry trata os eventos de expiração; eventos de tokens antigos são ignorados
+func (m *Model) handleExpiry(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case tokenExpiringMsg:
+		if msg.Token != m.Session.Token {
+			return nil
+		}
+		if m.refreshUnsupported {
+			return m.warnExpiry()
+		}
+		return refreshCmd(m.client(), false)
+
+	case tokenRefreshedMsg:
+		if msg.OldToken != m.Session.Token {
+			return nil
+		}
+		// O servidor já não aceita o token (revogado ou servidor reiniciado): só resta o login
+		if errors.Is(msg.Err, api.ErrUnauthorized) {
+			m.expireSession()
+			return nil
+		}
+		if msg.Err != nil {
+			if api.RefreshUnsupported(msg.Err) {
+				m.refreshUnsupported = true
+			}
+			// Sem renovação, um token recusado não volta a valer até o exp: só resta o login
+			if msg.Rejected {
+				m.expireSession()
+				return nil
+			}
+			return m.warnExpiry()
+		}
+		m.Session.Token = msg.Session.Token
+		m.WarningMsg = ""
+		if err := store.SaveSession(m.Profile.Name, m.Session); err != nil {
+			m.ErrorMsg = "Erro ao salvar sessão: " + err.Error()
+		}
+		// Um handshake recusado interrompe a reconexão; com o token novo ela recomeça
+		if m.ConnState == connReconnecting {
+			return tea.Batch(m.scheduleExpiry(), reconnectCmd(m.client()))
+		}
+		return m.scheduleExpiry()
+
+	case tokenExpiredMsg:
+		if msg.Token != m.Session.Token {
+			return nil
+		}
+		m.expireSession()
+	}
+	return nil
+}
+
+// handleUnauthorized trata um 401 de uma chamada fora do login (token revogado, servidor
+// reiniciado) como a expiração: tenta renovar o token e, se não for possível, volta ao login
+// lembrando a sala aberta. Retorna false se o erro não é de autenticação.
+func (m *Model) handleUnauthorized(err error) (tea.Cmd, bool) {
+	if !errors.Is(err, api.ErrUnauthorized) || m.Session.Token == "" {
+		return nil, false
+	}
+	if m.refreshUnsupported {
+		m.expireSession()
+		return nil, true
+	}
+	m.WarningMsg = "session rejected by the server, renewing…"
+	return refreshCmd(m.client(), true), true
+}
+
+// warnExpiry mostra o aviso de expiração e agenda a volta ao login
+func (m *Model) warnExpiry() tea.Cmd {
+	m.WarningMsg = fmt.Sprintf("session expires at %s, log in again to continue", m.TokenExpiry.Format("15:04"))
+	token := m.Session.Token
+	return tea.Tick(time.Until(m.TokenExpiry), func(time.Time) tea.Msg {
+		return tokenExpiredMsg{Token: token}
+	})
+}
+
+// expireSession volta para o login lembrando a sala atual, para reabri-la após a nova autenticação
+func (m *Model) expireSession() {
+	var room string
+	if m.State == chatView {
+		room = m.CurrentRoom
+	}
+	username := m.Session.Username
+
+	// O token já expirou: não há o que invalidar no servidor
+	store.DeleteSession(m.Profile.Name)
+	m.teardown()
+	m.ResumeRoom = room
+
+	// Já deixa o usuário preenchido para agilizar o novo login
+	m.UsernameInput.SetValue(username)
+	m.InputIndex = 1
+	m.UsernameInput.Blur()
+	m.PasswordInput.Focus()
+	m.ErrorMsg = "session expired, please log in again"
+}
+
+// resumeRoom reabre a sala que estava aberta quando a sessão expirou
+func (m *Model) resumeRoom() tea.Cmd {
+	roomId := m.ResumeRoom
+	m.ResumeRoom = ""
+	if roomId == "" {
+		return nil
+	}
+	for _, r := range m.Session.JoinedRooms {
+		if r.Id == roomId {
+			return m.enterRoom(roomId)
+		}
+	}
+	return nil
+}
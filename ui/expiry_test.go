@@ -0,0 +1,43 @@
+package ui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mellojp/chatli/api"
+	"github.com/mellojp/chatli/config"
+	"github.com/mellojp/chatli/data"
+)
+
+// TestRejectedTokenWithoutRefresh cobre o 401 no handshake da reconexão contra um servidor
+// sem /auth/refresh (404): a sessão deve voltar ao login em vez de esperar o exp do token
+func TestRejectedTokenWithoutRefresh(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	m := NewModel(&config.Config{}, &config.Profile{APIURL: ts.URL})
+	m.Session = data.Session{Token: "token", UserId: "1", Username: "alice", JoinedRooms: []data.Room{{Id: "sala"}}}
+	m.State = chatView
+	m.CurrentRoom = "sala"
+	m.ConnState = connReconnecting
+
+	rejected := &api.Error{Op: "/ws", StatusCode: http.StatusUnauthorized}
+	_, cmd := m.Update(reconnectResultMsg{Err: rejected})
+	if cmd == nil {
+		t.Fatal("401 na reconexão não pediu a renovação do token")
+	}
+	msg, ok := cmd().(tokenRefreshedMsg)
+	if !ok || !msg.Rejected || !api.RefreshUnsupported(msg.Err) {
+		t.Fatalf("resultado da renovação = %+v", msg)
+	}
+
+	m.Update(msg)
+	if m.State != loginView || m.Session.Token != "" {
+		t.Fatalf("estado %v com token %q, esperado login sem sessão", m.State, m.Session.Token)
+	}
+	if m.ResumeRoom != "sala" {
+		t.Errorf("ResumeRoom = %q, esperado a sala aberta", m.ResumeRoom)
+	}
+}
@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"github.com/mellojp/chatli/api"
+	"github.com/mellojp/chatli/data"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// historyPageSize é quantas mensagens são buscadas por página de histórico
+	historyPageSize = 50
+	// maxBackfillPages limita quantas páginas a reconexão busca para cobrir a queda
+	maxBackfillPages = 20
+)
+
+// olderPageMsg traz uma página de mensagens anteriores às já carregadas
+type olderPageMsg struct {
+	Session  uint64
+	RoomId   string
+	Messages []data.Message
+	Err      error
+}
+
+func loadOlderCmd(session uint64, c *api.Client, roomId, before string) tea.Cmd {
+	return func() tea.Msg {
+		messages, err := c.LoadChatMessagesPage(roomId, before, historyPageSize)
+		return olderPageMsg{Session: session, RoomId: roomId, Messages: messages, Err: err}
+	}
+}
+
+// loadOlder busca a página anterior da sala atual quando o viewport chega ao topo
+func (m *Model) loadOlder() tea.Cmd {
+	if m.loadingOlder || !m.Viewport.AtTop() || !m.hasMore[m.CurrentRoom] {
+		return nil
+	}
+	msgs := m.ChatsHistory[m.CurrentRoom].Messages()
+	if len(msgs) == 0 || msgs[0].Id == "" {
+		return nil
+	}
+	m.loadingOlder = true
+	return loadOlderCmd(m.session, m.client(), m.CurrentRoom, msgs[0].Id)
+}
+
+// handleOlderPage insere a página no início do histórico mantendo a posição de leitura
+func (m *Model) handleOlderPage(msg olderPageMsg) tea.Cmd {
+	// Página pedida por uma sessão já encerrada (logout ou troca de usuário)
+	if msg.Session != m.session {
+		return nil
+	}
+	m.loadingOlder = false
+	if msg.Err != nil {
+		if cmd, ok := m.handleUnauthorized(msg.Err); ok {
+			return cmd
+		}
+		m.ErrorMsg = describeError(msg.Err)
+		return nil
+	}
+	// A sala foi fechada enquanto a página chegava: o histórico dela já foi descartado
+	history, ok := m.ChatsHistory[msg.RoomId]
+	if !ok || !m.viewing(msg.RoomId) {
+		return nil
+	}
+	m.hasMore[msg.RoomId] = len(msg.Messages) == historyPageSize
+
+	before := m.Viewport.TotalLineCount()
+	history.Merge(msg.Messages)
+	offset := m.Viewport.YOffset
+	m.Viewport.SetContent(RenderChatView(m))
+	// As linhas novas entram acima; desloca o viewport para que o conteúdo visível não pule
+	m.Viewport.SetYOffset(offset + m.Viewport.TotalLineCount() - before)
+	return nil
+}
+
+// setFirstPage registra se a sala tem mais histórico além da primeira página carregada
+func (m *Model) setFirstPage(roomId string, page []data.Message) {
+	if _, ok := m.hasMore[roomId]; !ok {
+		m.hasMore[roomId] = len(page) == historyPageSize
+	}
+}
+
+// loadSince busca páginas do fim do histórico para trás até alcançar a última mensagem
+// conhecida (ou o início da sala), para que nenhuma mensagem da queda se perca
+func loadSince(c *api.Client, roomId string, last *data.Message) ([]data.Message, error) {
+	var all []data.Message
+	before := ""
+	for i := 0; i < maxBackfillPages; i++ {
+		page, err := c.LoadChatMessagesPage(roomId, before, historyPageSize)
+		if err != nil {
+			return nil, err
+		}
+		all = append(page, all...)
+		if len(page) < historyPageSize || len(page) == 0 {
+			break
+		}
+		oldest := page[0]
+		if last == nil || !oldest.SentAt.After(last.SentAt) || oldest.Id == "" {
+			break
+		}
+		before = oldest.Id
+	}
+	return all, nil
+}
@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/mellojp/chatli/config"
+	"github.com/mellojp/chatli/data"
+)
+
+// TestLeftRoomDropsLateResults confere que uma página anterior ou um backfill que chegam
+// depois de sair da sala não recriam o histórico descartado
+func TestLeftRoomDropsLateResults(t *testing.T) {
+	m := NewModel(&config.Config{}, &config.Profile{APIURL: "http://localhost:1"})
+	m.Session = data.Session{Token: "token", UserId: "1", Username: "alice"}
+	m.enterRoom("sala")
+	m.loadingOlder = true
+	m.leaveRoom()
+	if m.loadingOlder {
+		t.Error("leaveRoom manteve loadingOlder")
+	}
+
+	page := []data.Message{{Id: "1", RoomId: "sala", Content: "antiga"}}
+	m.Update(olderPageMsg{RoomId: "sala", Messages: page})
+	m.Update(backfillMsg{RoomId: "sala", Messages: page})
+	if _, ok := m.ChatsHistory["sala"]; ok {
+		t.Error("resultado tardio recriou o histórico da sala fechada")
+	}
+	if _, ok := m.hasMore["sala"]; ok {
+		t.Error("página tardia registrou hasMore da sala fechada")
+	}
+}
+
+// TestPreviousSessionResultsDropped confere que resultados pedidos antes do logout não
+// entram no histórico do próximo usuário, mesmo que ele abra a mesma sala
+func TestPreviousSessionResultsDropped(t *testing.T) {
+	m := NewModel(&config.Config{}, &config.Profile{APIURL: "http://localhost:1"})
+	m.Session = data.Session{Token: "token-a", UserId: "1", Username: "alice"}
+	m.enterRoom("sala")
+	page := []data.Message{{Id: "1", RoomId: "sala", Content: "de alice"}}
+	older := olderPageMsg{Session: m.session, RoomId: "sala", Messages: page}
+	backfill := backfillMsg{Session: m.session, RoomId: "sala", Messages: page}
+
+	m.teardown()
+	m.Session = data.Session{Token: "token-b", UserId: "2", Username: "bob"}
+	m.enterRoom("sala")
+	m.Update(older)
+	m.Update(backfill)
+	if n := m.ChatsHistory["sala"].Len(); n != 0 {
+		t.Errorf("histórico do novo usuário com %d mensagens da sessão anterior", n)
+	}
+}
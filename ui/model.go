@@ -1,17 +1,21 @@
 package ui
 
 import (
+	"context"
 	"strings"
+	"time"
 
 	"github.com/mellojp/chatli/api"
+	"github.com/mellojp/chatli/config"
 	"github.com/mellojp/chatli/data"
+	"github.com/mellojp/chatli/store"
 
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/gorilla/websocket"
 )
 
 type uiState int
@@ -23,15 +27,17 @@ const (
 	createRoomView
 	chatView
 	joinRoomView
+	profileView
 )
 
 type SocketError struct {
-	Err error
+	Conn *api.Conn
+	Err  error
 }
 
 type Model struct {
 	State        uiState
-	ChatsHistory map[string][]data.Message
+	ChatsHistory map[string]*data.MessageStore
 
 	// Inputs específicos
 	UsernameInput textinput.Model
@@ -42,6 +48,7 @@ type Model struct {
 	InputIndex int // 0 para User, 1 para Password
 
 	Viewport    viewport.Model
+	Spinner     spinner.Model
 	Cursor      int
 	CurrentRoom string
 	Session     data.Session
@@ -50,10 +57,65 @@ type Model struct {
 	WindowWidth  int
 	ErrorMsg     string
 	SuccessMsg   string
-	WSConn       *websocket.Conn
+	WarningMsg   string
+	WSConn       *api.Conn
+	ConnState    connState
+	Latency      time.Duration
+	// Config traz os perfis de servidor; Profile é o perfil em uso e Client aponta para ele
+	Config  *config.Config
+	Profile config.Profile
+	Client  *api.Client
+	// TokenExpiry é a expiração do token da sessão (zero se desconhecida)
+	TokenExpiry time.Time
+	// ResumeRoom é a sala a reabrir após um novo login por expiração da sessão
+	ResumeRoom string
+	// Outbox guarda as mensagens digitadas que o servidor ainda não confirmou
+	Outbox *store.Outbox
+	// ReadMarkers guarda até onde o usuário leu cada sala, entre execuções
+	ReadMarkers *store.ReadMarkers
+
+	// cancel aborta a requisição em andamento (login ou carregamento de histórico);
+	// request é o número dela, levado pelo resultado
+	cancel  context.CancelFunc
+	request uint64
+	// session conta as sessões encerradas por teardown; resultados que não vêm de uma
+	// requisição numerada (backfill, páginas anteriores) levam o número e são descartados
+	// se ele mudou
+	session uint64
+	// backoff controla o intervalo entre tentativas de reconexão do websocket
+	backoff *api.Backoff
+	// written marca as mensagens da outbox já escritas na conexão, aguardando o eco
+	written  map[string]bool
+	flushing bool
+	// unverified marca as mensagens restauradas da outbox que ainda não foram conferidas com
+	// o histórico: podem ter sido entregues antes de o app fechar
+	unverified map[string]bool
+	// hasMore indica, por sala, se ainda há histórico mais antigo no servidor
+	hasMore      map[string]bool
+	loadingOlder bool
+	// refreshUnsupported evita tentar renovar o token num servidor sem o endpoint
+	refreshUnsupported bool
+	// notices são as linhas de sistema de cada sala (entradas, saídas, avisos do servidor)
+	notices map[string][]data.Message
+	// typing guarda, por sala, até quando cada usuário aparece como digitando
+	typing map[string]map[string]time.Time
+	// presence é o último status conhecido de cada usuário, pelo id
+	presence map[string]string
+	// unread conta as mensagens novas das salas que não estão abertas; mentions, quantas
+	// delas citam o usuário
+	unread   map[string]int
+	mentions map[string]int
+	// activity é o horário da última mensagem conhecida de cada sala, para ordenar a lista
+	activity map[string]time.Time
+	// unreadPushed indica que o servidor já mandou algum room.unread nesta sessão
+	unreadPushed bool
+	// savingMarkers indica que a gravação dos marcadores de leitura já está agendada
+	savingMarkers bool
 }
 
-func NewModel() *Model {
+// NewModel cria o modelo da UI. Sem um perfil informado nem um perfil padrão na
+// configuração, a UI começa pela escolha do perfil.
+func NewModel(cfg *config.Config, profile *config.Profile) *Model {
 	// Configuração do Input de Username
 	userIn := textinput.New()
 	userIn.Placeholder = "Username"
@@ -82,20 +144,52 @@ func NewModel() *Model {
 
 	vp := viewport.New(80, 20)
 
-	return &Model{
+	// Indicador de carregamento das requisições
+	sp := spinner.New()
+	sp.Spinner = spinner.MiniDot
+	sp.Style = SystemStyle
+
+	m := &Model{
 		State:         loginView,
-		ChatsHistory:  make(map[string][]data.Message),
+		ChatsHistory:  make(map[string]*data.MessageStore),
 		UsernameInput: userIn,
 		PasswordInput: passIn,
 		ChatInput:     chatIn,
 		GenericInput:  genIn,
 		InputIndex:    0,
 		Viewport:      vp,
+		Spinner:       sp,
+		Config:        cfg,
+		Outbox:        store.NewMemoryOutbox(),
+		ReadMarkers:   store.NewMemoryReadMarkers(),
+		backoff:       api.NewBackoff(),
+		written:       make(map[string]bool),
+		unverified:    make(map[string]bool),
+		hasMore:       make(map[string]bool),
+		notices:       make(map[string][]data.Message),
+		typing:        make(map[string]map[string]time.Time),
+		presence:      make(map[string]string),
+		unread:        make(map[string]int),
+		mentions:      make(map[string]int),
+		activity:      make(map[string]time.Time),
+	}
+
+	if profile != nil {
+		m.useProfile(*profile)
+	} else if p, ok := cfg.Default(); ok {
+		m.useProfile(p)
+	} else {
+		m.State = profileView
+		m.UsernameInput.Blur()
 	}
+	return m
 }
 
-func (*Model) Init() tea.Cmd {
-	return tea.Batch(textarea.Blink, textinput.Blink)
+func (m *Model) Init() tea.Cmd {
+	if m.State == profileView {
+		return tea.Batch(textarea.Blink, textinput.Blink)
+	}
+	return tea.Batch(textarea.Blink, textinput.Blink, m.restoreSession())
 }
 
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -106,7 +200,8 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "ctrl+c":
-			return m, tea.Quit
+			// Marcadores de leitura ainda não gravados são salvos antes de sair
+			return m, tea.Sequence(saveReadMarkersCmd(m.ReadMarkers), tea.Quit)
 
 		// Navegação via TAB e SETAS (Troca de Campo ou Navegação na Lista)
 		case "tab", "shift+tab", "up", "down":
@@ -134,40 +229,40 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 				}
 				return m, nil
+			case profileView:
+				if msg.String() == "up" {
+					if m.Cursor > 0 {
+						m.Cursor--
+					}
+				} else {
+					if m.Cursor < len(m.Config.Profiles)-1 {
+						m.Cursor++
+					}
+				}
+				return m, nil
 			}
 
 		case "enter":
+			// Ignora envios repetidos enquanto uma requisição está em andamento
+			if m.pending() && m.State != chatView {
+				return m, nil
+			}
 			m.ErrorMsg = ""
 			m.SuccessMsg = ""
 			switch m.State {
+			case profileView:
+				names := m.Config.Names()
+				if len(names) == 0 {
+					return m, nil
+				}
+				p, _ := m.Config.Profile(names[m.Cursor])
+				return m, m.selectProfile(p)
+
 			case loginView:
 				// Só tenta logar se o campo de password estiver focado
 				if m.InputIndex == 1 {
-					session, err := api.Login(m.UsernameInput.Value(), m.PasswordInput.Value())
-					if err != nil {
-						m.ErrorMsg = err.Error()
-						return m, nil
-					}
-					m.Session = *session
-
-					// Carrega as salas do usuário do servidor
-					rooms, err := api.GetUserRooms(m.Session)
-					if err == nil {
-						m.Session.JoinedRooms = rooms
-					}
-
-					// Conecta WebSocket Global
-					wsConn, err := api.ConnectWebSocket(m.Session)
-					if err != nil {
-						m.ErrorMsg = "Erro WS: " + err.Error()
-						return m, nil
-					}
-					m.WSConn = wsConn
-
-					m.State = roomListView
-					m.UsernameInput.Reset()
-					m.PasswordInput.Reset()
-					return m, WaitForMessage(m.WSConn)
+					ctx, req := m.startRequest()
+					return m, tea.Batch(loginCmd(ctx, req, m.client(), m.UsernameInput.Value(), m.PasswordInput.Value()), m.Spinner.Tick)
 				}
 				// Se ENTER for pressionado no campo de username, não faz nada
 				return m, nil
@@ -175,17 +270,8 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case registerView:
 				// Só tenta registrar se o campo de password estiver focado
 				if m.InputIndex == 1 {
-					err := api.Register(m.UsernameInput.Value(), m.PasswordInput.Value())
-					if err != nil {
-						m.ErrorMsg = err.Error()
-						return m, nil
-					}
-					m.State = loginView
-					m.SuccessMsg = "Registrado! Faça login."
-					m.InputIndex = 1 // Foca na senha pra agilizar no login
-					m.UsernameInput.Blur()
-					m.PasswordInput.Focus()
-					return m, nil
+					ctx, req := m.startRequest()
+					return m, tea.Batch(registerCmd(ctx, req, m.client(), m.UsernameInput.Value(), m.PasswordInput.Value()), m.Spinner.Tick)
 				}
 				// Se ENTER for pressionado no campo de username, não faz nada
 				return m, nil
@@ -194,74 +280,52 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if roomName == "" {
 					return m, nil
 				}
-				newRoom, err := api.CreateRoom(m.Session, roomName)
-				if err != nil {
-					m.ErrorMsg = err.Error()
-					return m, nil
-				}
-				m.Session.JoinedRooms = append(m.Session.JoinedRooms, *newRoom)
-				m.State = roomListView
-				m.GenericInput.Reset()
-				return m, nil
+				ctx, req := m.startRequest()
+				return m, tea.Batch(createRoomCmd(ctx, req, m.client(), roomName), m.Spinner.Tick)
 
 			case joinRoomView:
 				roomId := m.GenericInput.Value()
-				err := api.JoinRoom(m.Session, roomId)
-				if err != nil {
-					m.ErrorMsg = err.Error()
+				if roomId == "" {
 					return m, nil
 				}
-
-				// Atualiza lista de salas para pegar o nome
-				rooms, err := api.GetUserRooms(m.Session)
-				if err == nil {
-					m.Session.JoinedRooms = rooms
-				}
-
-				m.State = chatView
-				m.CurrentRoom = roomId
-
-				// Carrega histórico da nova sala
-				v, _ := api.LoadChatMessages(m.Session, m.CurrentRoom)
-				m.ChatsHistory[m.CurrentRoom] = v
-				m.Viewport.SetContent(RenderChatView(m))
-				m.Viewport.GotoBottom()
-
-				m.GenericInput.Reset()
-				m.ChatInput.Focus()
-				return m, nil
+				ctx, req := m.startRequest()
+				return m, tea.Batch(joinRoomCmd(ctx, req, m.client(), roomId), m.Spinner.Tick)
 
 			case chatView:
 				content := m.ChatInput.Value()
 				if content == "" {
 					return m, nil
 				}
-				// Usa conexao global
+				// Vai para a outbox e é enviada pela conexao global assim que possível
 				msg := data.Message{
-					Type:    "chat",
-					Content: content,
-					RoomId:  m.CurrentRoom,
-					// Id e SentAt são gerados no servidor
-				}
-				if err := m.WSConn.WriteJSON(msg); err != nil {
-					m.ErrorMsg = "Erro ao enviar: " + err.Error()
-					return m, nil
+					Type:           "chat",
+					UserId:         m.Session.UserId,
+					SenderUsername: m.Session.Username,
+					Content:        content,
+					RoomId:         m.CurrentRoom,
+					SentAt:         time.Now(),
+					Nonce:          data.NewNonce(),
+					// Id é gerado no servidor
 				}
 				m.ChatInput.Reset()
-				return m, nil
+				cmd = m.queueMessage(msg)
+				m.Viewport.SetContent(RenderChatView(m))
+				m.Viewport.GotoBottom()
+				return m, cmd
 			case roomListView:
 				if len(m.Session.JoinedRooms) == 0 {
 					return m, nil
 				}
-				m.CurrentRoom = m.Session.JoinedRooms[m.Cursor].Id
-				m.State = chatView
-
-				v, _ := api.LoadChatMessages(m.Session, m.CurrentRoom)
-				m.ChatsHistory[m.CurrentRoom] = v
-				m.Viewport.SetContent(RenderChatView(m))
-				m.Viewport.GotoBottom()
-				m.ChatInput.Focus()
+				return m, m.enterRoom(m.sortedRooms()[m.Cursor].Id)
+			}
 
+		case "ctrl+p":
+			// Troca de perfil, apenas antes do login
+			if m.State == loginView && !m.pending() && len(m.Config.Profiles) > 1 {
+				m.ErrorMsg = ""
+				m.SuccessMsg = ""
+				m.State = profileView
+				m.Cursor = 0
 				return m, nil
 			}
 
@@ -285,14 +349,15 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "esc":
 			m.ErrorMsg = ""
 			m.SuccessMsg = ""
+			// Com um login em andamento o esc apenas o cancela
+			if m.State == loginView && m.cancel != nil {
+				m.cancelRequest()
+				return m, nil
+			}
+			m.cancelRequest()
 			switch m.State {
 			case roomListView:
-				m.State = loginView
-				m.UsernameInput.Reset()
-				m.PasswordInput.Reset()
-				m.InputIndex = 0
-				m.UsernameInput.Focus()
-				m.PasswordInput.Blur()
+				return m, m.logout()
 			case loginView:
 				m.State = registerView
 				m.ErrorMsg = ""
@@ -308,13 +373,16 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.InputIndex = 0
 				m.UsernameInput.Focus()
 				m.PasswordInput.Blur()
-			case joinRoomView, chatView, createRoomView:
+			case chatView:
+				return m, m.leaveRoom()
+			case joinRoomView, createRoomView:
 				m.State = roomListView
 			}
 		case "pgup", "pgdown":
 			if m.State == chatView {
 				m.Viewport, cmd = m.Viewport.Update(msg)
-				return m, cmd
+				// Ao chegar ao topo, busca a página anterior do histórico
+				return m, tea.Batch(cmd, m.loadOlder())
 			}
 		case "left", "right":
 			if m.State == chatView {
@@ -338,13 +406,143 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.Viewport.Height = m.WindowHeight - 4 - m.ChatInput.Height() - 1 // Ajusta para o chat input
 		m.Viewport.Width = m.WindowWidth
 
-	case data.Message:
-		m.ChatsHistory[msg.RoomId] = append(m.ChatsHistory[msg.RoomId], msg)
-		if m.CurrentRoom == msg.RoomId {
+	case spinner.TickMsg:
+		// O spinner só continua girando enquanto houver requisição pendente
+		if !m.pending() {
+			return m, nil
+		}
+		m.Spinner, cmd = m.Spinner.Update(msg)
+		return m, cmd
+
+	case loginResultMsg:
+		// Login cancelado pelo usuário: descarta o resultado mesmo que tenha concluído
+		if !m.finishRequest(msg.Req, msg.Err) {
+			if msg.Conn != nil {
+				msg.Conn.Close()
+			}
+			return m, nil
+		}
+		if msg.Err != nil {
+			if msg.Session.Token != "" {
+				m.ErrorMsg = "Erro WS: " + msg.Err.Error()
+			} else {
+				m.ErrorMsg = describeError(msg.Err)
+			}
+			return m, nil
+		}
+		m.Session = msg.Session
+		m.openOutbox()
+		m.openReadMarkers()
+		if err := store.SaveSession(m.Profile.Name, m.Session); err != nil {
+			m.ErrorMsg = "Erro ao salvar sessão: " + err.Error()
+		}
+		m.refreshUnsupported = false
+
+		m.State = roomListView
+		m.UsernameInput.Reset()
+		m.PasswordInput.Reset()
+		return m, tea.Batch(
+			m.startConnection(msg.Conn),
+			m.scheduleExpiry(),
+			m.unreadFallback(),
+			m.resumeRoom(),
+		)
+
+	case registerResultMsg:
+		if !m.finishRequest(msg.Req, msg.Err) {
+			return m, nil
+		}
+		if msg.Err != nil {
+			m.ErrorMsg = describeError(msg.Err)
+			return m, nil
+		}
+		m.State = loginView
+		m.SuccessMsg = "Registrado! Faça login."
+		m.InputIndex = 1 // Foca na senha pra agilizar no login
+		m.UsernameInput.Blur()
+		m.PasswordInput.Focus()
+		return m, nil
+
+	case roomCreatedMsg:
+		if !m.finishRequest(msg.Req, msg.Err) {
+			return m, nil
+		}
+		if msg.Err != nil {
+			if cmd, ok := m.handleUnauthorized(msg.Err); ok {
+				return m, cmd
+			}
+			m.ErrorMsg = describeError(msg.Err)
+			return m, nil
+		}
+		m.Session.JoinedRooms = append(m.Session.JoinedRooms, *msg.Room)
+		m.State = roomListView
+		m.GenericInput.Reset()
+		return m, nil
+
+	case roomJoinedMsg:
+		if !m.finishRequest(msg.Req, msg.Err) {
+			return m, nil
+		}
+		if msg.Err != nil {
+			if cmd, ok := m.handleUnauthorized(msg.Err); ok {
+				return m, cmd
+			}
+			m.ErrorMsg = describeError(msg.Err)
+			return m, nil
+		}
+		m.Session.JoinedRooms = msg.Rooms
+		m.GenericInput.Reset()
+		return m, m.enterRoom(msg.RoomId)
+
+	case historyLoadedMsg:
+		if !m.finishRequest(msg.Req, msg.Err) {
+			return m, nil
+		}
+		if msg.Err != nil {
+			if cmd, ok := m.handleUnauthorized(msg.Err); ok {
+				return m, cmd
+			}
+			m.ErrorMsg = describeError(msg.Err)
+			return m, nil
+		}
+		m.setFirstPage(msg.RoomId, msg.Messages)
+		m.roomHistory(msg.RoomId).Merge(msg.Messages)
+		cmd = m.reconcileOutbox(msg.RoomId, msg.Messages)
+		if m.viewing(msg.RoomId) {
+			cmd = tea.Batch(cmd, m.markRead(msg.RoomId))
 			m.Viewport.SetContent(RenderChatView(m))
 			m.Viewport.GotoBottom()
 		}
-		return m, WaitForMessage(m.WSConn)
+		return m, cmd
+
+	case olderPageMsg:
+		return m, m.handleOlderPage(msg)
+
+	case unreadFallbackMsg, unreadLoadedMsg:
+		return m, m.handleUnreadFallback(msg)
+
+	case readMarkersSavedMsg:
+		return m, m.handleMarkersSaved(msg)
+
+	case flushResultMsg:
+		return m, m.handleFlushResult(msg)
+
+	case outboxCheckedMsg:
+		return m, m.handleOutboxChecked(msg)
+
+	case tokenExpiringMsg, tokenRefreshedMsg, tokenExpiredMsg:
+		return m, m.handleExpiry(msg)
+
+	case SocketError, reconnectMsg, reconnectResultMsg, latencyMsg, backfillMsg:
+		return m, m.handleConnection(msg)
+
+	case messageCreatedMsg, messageEditedMsg, messageDeletedMsg, memberJoinedMsg, memberLeftMsg,
+		typingMsg, presenceMsg, roomRenamedMsg, systemNoticeMsg, roomUnreadMsg, unknownEventMsg:
+		return m, m.handleEvent(msg.(interface{ source() *api.Conn }))
+
+	case typingExpiredMsg:
+		m.refreshRoom(m.CurrentRoom)
+		return m, nil
 	}
 
 	// Update dos TextAreas
@@ -369,6 +567,34 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// receiveMessage registra uma mensagem recebida pelo websocket
+func (m *Model) receiveMessage(msg data.Message) tea.Cmd {
+	m.confirmDelivery(msg)
+	// Ecos repetidos ou mensagens já trazidas pelo histórico são descartados pelo store
+	added := m.roomHistory(msg.RoomId).Add(msg)
+	if !m.viewing(msg.RoomId) {
+		if added {
+			m.countIncoming(msg)
+			m.touchRoom(msg.RoomId, msg.SentAt)
+		}
+		return nil
+	}
+	cmd := m.markRead(msg.RoomId)
+	m.Viewport.SetContent(RenderChatView(m))
+	m.Viewport.GotoBottom()
+	return cmd
+}
+
+// roomHistory retorna o store de mensagens da sala, criando-o se necessário
+func (m *Model) roomHistory(roomId string) *data.MessageStore {
+	h, ok := m.ChatsHistory[roomId]
+	if !ok {
+		h = data.NewMessageStore()
+		m.ChatsHistory[roomId] = h
+	}
+	return h
+}
+
 func (m *Model) View() string {
 	var s string
 	switch m.State {
@@ -376,6 +602,8 @@ func (m *Model) View() string {
 		s = RenderLogin(m)
 	case registerView:
 		s = RenderRegister(m)
+	case profileView:
+		s = RenderProfiles(m)
 	case roomListView:
 		s = RenderRoomsList(m)
 	case createRoomView:
@@ -395,32 +623,36 @@ func (m *Model) View() string {
 		}
 
 		title := RoomTitleStyle.Render("Room: " + roomName)
-		back := HelpStyle.Render("[esc] back")
+		back := renderConnState(m) + "  " + HelpStyle.Render("[esc] back")
 		gap := m.WindowWidth - lipgloss.Width(title) - lipgloss.Width(back)
 		if gap < 0 {
 			gap = 0
 		}
 
 		header := title + strings.Repeat(" ", gap) + back + "\n"
-		subheader := RoomIdStyle.Render("ID: "+m.CurrentRoom) + "\n"
+		subheader := RoomIdStyle.Render("ID: " + m.CurrentRoom)
+		if m.loadingOlder {
+			subheader += HelpStyle.Render("  loading older messages…")
+		} else if m.hasMore[m.CurrentRoom] {
+			subheader += HelpStyle.Render("  [pgup] older messages")
+		}
+		if typing := renderTyping(m); typing != "" {
+			subheader += NoticeStyle.Render("  " + typing)
+		}
+		subheader += "\n"
 		separator := HelpStyle.Render(strings.Repeat("─", m.WindowWidth)) + "\n"
 		body := m.Viewport.View()
 		prompt := SystemStyle.Render("$ ") + InputStyle.Render(m.ChatInput.View())
 		s = header + subheader + separator + body + "\n" + separator + prompt
+		if m.pending() {
+			s += "\n" + renderLoading(m)
+		}
+		if m.WarningMsg != "" {
+			s += "\n" + WarningStyle.Render("warning: "+m.WarningMsg)
+		}
 		if m.ErrorMsg != "" {
 			s += "\n" + ErrorStyle.Render("error: "+m.ErrorMsg)
 		}
 	}
 	return lipgloss.Place(m.WindowWidth, m.WindowHeight, lipgloss.Left, lipgloss.Top, AppStyle.Render(s))
 }
-
-func WaitForMessage(conn *websocket.Conn) tea.Cmd {
-	return func() tea.Msg {
-		msg := data.Message{}
-		err := conn.ReadJSON(&msg)
-		if err != nil {
-			return SocketError{Err: err}
-		}
-		return msg
-	}
-}
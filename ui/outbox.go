@@ -0,0 +1,174 @@
+package ui
+
+import (
+	"context"
+
+	"github.com/mellojp/chatli/api"
+	"github.com/mellojp/chatli/data"
+	"github.com/mellojp/chatli/store"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// flushResultMsg indica quais mensagens da outbox foram escritas no socket
+type flushResultMsg struct {
+	Conn    *api.Conn
+	Written []string
+	Err     error
+}
+
+// flushCmd envia as mensagens em ordem, parando no primeiro erro
+func flushCmd(conn *api.Conn, msgs []data.Message) tea.Cmd {
+	return func() tea.Msg {
+		var written []string
+		for _, msg := range msgs {
+			ctx, cancel := context.WithTimeout(context.Background(), api.DefaultTimeout)
+			err := conn.Send(ctx, msg)
+			cancel()
+			if err != nil {
+				return flushResultMsg{Conn: conn, Written: written, Err: err}
+			}
+			written = append(written, msg.Nonce)
+		}
+		return flushResultMsg{Conn: conn, Written: written}
+	}
+}
+
+// outboxCheckedMsg traz o histórico recente de uma sala com mensagens restauradas da outbox
+type outboxCheckedMsg struct {
+	Outbox   *store.Outbox
+	RoomId   string
+	Messages []data.Message
+	Err      error
+}
+
+func checkOutboxCmd(c *api.Client, outbox *store.Outbox, roomId string, oldest data.Message) tea.Cmd {
+	return func() tea.Msg {
+		messages, err := loadSince(c, roomId, &oldest)
+		return outboxCheckedMsg{Outbox: outbox, RoomId: roomId, Messages: messages, Err: err}
+	}
+}
+
+// openOutbox carrega a outbox persistida do usuário logado. As mensagens restauradas podem
+// ter sido escritas antes de o app fechar, sem que o eco chegasse: elas só são reenviadas
+// depois de conferidas com o histórico da sala.
+func (m *Model) openOutbox() {
+	outbox, err := store.OpenOutbox(m.Profile.Name, m.Session.UserId)
+	if err != nil {
+		m.ErrorMsg = "Erro ao abrir outbox: " + err.Error()
+	}
+	m.Outbox = outbox
+	m.written = make(map[string]bool)
+	m.unverified = make(map[string]bool)
+	for _, msg := range outbox.All() {
+		m.unverified[msg.Nonce] = true
+	}
+}
+
+// checkOutbox busca o histórico das salas com mensagens restauradas ainda não conferidas.
+// Roda a cada conexão nova, até que a busca de cada sala dê certo.
+func (m *Model) checkOutbox() tea.Cmd {
+	oldest := make(map[string]data.Message)
+	for _, msg := range m.Outbox.All() {
+		if _, ok := oldest[msg.RoomId]; !ok && m.unverified[msg.Nonce] {
+			oldest[msg.RoomId] = msg
+		}
+	}
+	var cmds []tea.Cmd
+	for roomId, msg := range oldest {
+		cmds = append(cmds, checkOutboxCmd(m.client(), m.Outbox, roomId, msg))
+	}
+	return tea.Batch(cmds...)
+}
+
+// handleOutboxChecked confirma as mensagens restauradas que já estão no histórico e libera
+// as demais para envio
+func (m *Model) handleOutboxChecked(msg outboxCheckedMsg) tea.Cmd {
+	// Resultado de uma sessão anterior
+	if msg.Outbox != m.Outbox {
+		return nil
+	}
+	if msg.Err != nil {
+		if cmd, ok := m.handleUnauthorized(msg.Err); ok {
+			return cmd
+		}
+		return nil
+	}
+	for _, pending := range m.Outbox.Pending(msg.RoomId) {
+		delete(m.unverified, pending.Nonce)
+	}
+	return m.reconcileOutbox(msg.RoomId, msg.Messages)
+}
+
+// queueMessage coloca a mensagem na outbox e tenta enviá-la
+func (m *Model) queueMessage(msg data.Message) tea.Cmd {
+	if err := m.Outbox.Add(msg); err != nil {
+		m.ErrorMsg = "Erro ao salvar outbox: " + err.Error()
+	}
+	return m.flushOutbox()
+}
+
+// flushOutbox envia, em ordem, as mensagens da outbox que ainda não foram escritas na conexão.
+// Apenas um flush roda por vez para preservar a ordem de envio.
+func (m *Model) flushOutbox() tea.Cmd {
+	if m.WSConn == nil || m.ConnState != connOnline || m.flushing {
+		return nil
+	}
+	var msgs []data.Message
+	for _, msg := range m.Outbox.All() {
+		if !m.written[msg.Nonce] && !m.unverified[msg.Nonce] {
+			msgs = append(msgs, msg)
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	m.flushing = true
+	return flushCmd(m.WSConn, msgs)
+}
+
+// handleFlushResult registra as mensagens escritas e continua o flush se sobrou algo
+func (m *Model) handleFlushResult(msg flushResultMsg) tea.Cmd {
+	if msg.Conn != m.WSConn {
+		return nil
+	}
+	m.flushing = false
+	for _, nonce := range msg.Written {
+		m.written[nonce] = true
+	}
+	if msg.Err != nil {
+		// A queda da conexão é tratada pelo supervisor; a mensagem continua pendente
+		return nil
+	}
+	return m.flushOutbox()
+}
+
+// confirmDelivery marca como enviada a mensagem cujo eco chegou do servidor
+func (m *Model) confirmDelivery(msg data.Message) {
+	if msg.Nonce == "" {
+		return
+	}
+	m.Outbox.Remove(msg.Nonce)
+	delete(m.written, msg.Nonce)
+	delete(m.unverified, msg.Nonce)
+}
+
+// reconcileOutbox confronta a outbox com o histórico recém-carregado de uma sala:
+// mensagens presentes no histórico foram entregues; as escritas numa conexão que caiu
+// antes do eco voltam para a fila de envio.
+func (m *Model) reconcileOutbox(roomId string, history []data.Message) tea.Cmd {
+	delivered := make(map[string]bool)
+	for _, msg := range history {
+		if msg.Nonce != "" {
+			delivered[msg.Nonce] = true
+		}
+	}
+	for _, msg := range m.Outbox.Pending(roomId) {
+		if delivered[msg.Nonce] {
+			m.confirmDelivery(msg)
+		} else {
+			delete(m.written, msg.Nonce)
+		}
+	}
+	return m.flushOutbox()
+}
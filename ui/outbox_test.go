@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mellojp/chatli/config"
+	"github.com/mellojp/chatli/data"
+	"github.com/mellojp/chatli/store"
+)
+
+// TestRestoredOutboxCheckedBeforeFlush confere que as mensagens restauradas do disco só são
+// reenviadas depois de conferidas com o histórico, para não duplicar as já entregues
+func TestRestoredOutboxCheckedBeforeFlush(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	sent := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	delivered := data.Message{RoomId: "sala", UserId: "1", Content: "entregue", Nonce: "n1", SentAt: sent}
+	lost := data.Message{RoomId: "sala", UserId: "1", Content: "perdida", Nonce: "n2", SentAt: sent.Add(time.Second)}
+
+	previous, err := store.OpenOutbox("work", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous.Add(delivered)
+	previous.Add(lost)
+
+	history := []data.Message{{Id: "10", RoomId: "sala", UserId: "1", Content: "entregue", Nonce: "n1", SentAt: sent}}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(history)
+	}))
+	defer ts.Close()
+
+	m := NewModel(&config.Config{}, &config.Profile{Name: "work", APIURL: ts.URL})
+	m.Session = data.Session{Token: "token", UserId: "1", Username: "alice"}
+	m.openOutbox()
+	if !m.unverified["n1"] || !m.unverified["n2"] {
+		t.Fatalf("mensagens restauradas não ficaram retidas: %v", m.unverified)
+	}
+
+	cmd := m.checkOutbox()
+	if cmd == nil {
+		t.Fatal("outbox restaurada não foi conferida")
+	}
+	checked, ok := cmd().(outboxCheckedMsg)
+	if !ok || checked.Err != nil {
+		t.Fatalf("conferência = %+v", checked)
+	}
+	m.Update(checked)
+
+	if m.Outbox.Has("n1") {
+		t.Error("mensagem já entregue continua na outbox e seria reenviada")
+	}
+	if !m.Outbox.Has("n2") || m.unverified["n2"] {
+		t.Error("mensagem não entregue deveria ficar liberada para envio")
+	}
+	if m.checkOutbox() != nil {
+		t.Error("outbox já conferida foi conferida de novo")
+	}
+}
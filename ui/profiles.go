@@ -0,0 +1,38 @@
+package ui
+
+import (
+	"github.com/mellojp/chatli/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// useProfile passa a usar o servidor do perfil, com o usuário padrão já preenchido
+func (m *Model) useProfile(p config.Profile) {
+	m.Profile = p
+	client, err := p.NewClient()
+	if err != nil {
+		m.ErrorMsg = err.Error()
+	}
+	m.Client = client
+
+	m.State = loginView
+	m.Cursor = 0
+	m.UsernameInput.Reset()
+	m.PasswordInput.Reset()
+	if p.Username != "" {
+		m.UsernameInput.SetValue(p.Username)
+		m.InputIndex = 1
+		m.UsernameInput.Blur()
+		m.PasswordInput.Focus()
+	} else {
+		m.InputIndex = 0
+		m.UsernameInput.Focus()
+		m.PasswordInput.Blur()
+	}
+}
+
+// selectProfile troca para o perfil escolhido e tenta a sessão salva dele
+func (m *Model) selectProfile(p config.Profile) tea.Cmd {
+	m.useProfile(p)
+	return m.restoreSession()
+}
@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mellojp/chatli/api"
+	"github.com/mellojp/chatli/data"
+	"github.com/mellojp/chatli/store"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// restoreSessionCmd valida a sessão salva buscando as salas do usuário e reabre o websocket
+func restoreSessionCmd(ctx context.Context, req uint64, c *api.Client, profile string) tea.Cmd {
+	return func() tea.Msg {
+		s := c.Session
+		rooms, err := c.GetUserRoomsContext(ctx)
+		if err != nil {
+			// Token expirado ou revogado: a sessão salva não serve mais
+			if errors.Is(err, api.ErrUnauthorized) {
+				store.DeleteSession(profile)
+			}
+			return loginResultMsg{Req: req, Err: err}
+		}
+		s.JoinedRooms = rooms
+
+		conn, err := c.Connect(ctx)
+		if err != nil {
+			return loginResultMsg{Req: req, Session: s, Err: err}
+		}
+		return loginResultMsg{Req: req, Session: s, Conn: conn}
+	}
+}
+
+// serverLogoutCmd avisa o servidor para invalidar o token; falhas são ignoradas
+// porque a sessão local já foi descartada
+func serverLogoutCmd(c *api.Client) tea.Cmd {
+	return func() tea.Msg {
+		c.LogoutContext(context.Background())
+		return nil
+	}
+}
+
+// restoreSession tenta entrar direto com a sessão salva da última execução
+func (m *Model) restoreSession() tea.Cmd {
+	s, err := store.LoadSession(m.Profile.Name)
+	if err != nil || s == nil {
+		return nil
+	}
+	c := m.client()
+	c.Session = *s
+	ctx, req := m.startRequest()
+	return tea.Batch(restoreSessionCmd(ctx, req, c, m.Profile.Name), m.Spinner.Tick)
+}
+
+// logout encerra a sessão do usuário: invalida o token no servidor, apaga a sessão salva,
+// fecha o websocket e descarta todo o estado do usuário antes de voltar para o login
+func (m *Model) logout() tea.Cmd {
+	var cmd tea.Cmd
+	if m.Session.Token != "" {
+		cmd = serverLogoutCmd(m.client())
+	}
+	if err := store.DeleteSession(m.Profile.Name); err != nil {
+		m.ErrorMsg = "Erro ao apagar sessão: " + err.Error()
+	}
+	m.teardown()
+	return cmd
+}
+
+// teardown cancela o que está em andamento, fecha a conexão e limpa os dados do usuário,
+// evitando que o próximo login veja mensagens ou a sessão do anterior
+func (m *Model) teardown() {
+	m.cancelRequest()
+	m.session++
+	if m.WSConn != nil {
+		m.WSConn.Close()
+		m.WSConn = nil
+	}
+	m.ConnState = connOffline
+	m.Latency = 0
+	m.backoff.Reset()
+
+	m.Session = data.Session{}
+	m.ChatsHistory = make(map[string]*data.MessageStore)
+	m.hasMore = make(map[string]bool)
+	m.notices = make(map[string][]data.Message)
+	m.typing = make(map[string]map[string]time.Time)
+	m.presence = make(map[string]string)
+	m.unread = make(map[string]int)
+	m.mentions = make(map[string]int)
+	m.activity = make(map[string]time.Time)
+	// Uma gravação já agendada ainda salva os marcadores desta sessão
+	m.ReadMarkers = store.NewMemoryReadMarkers()
+	m.savingMarkers = false
+	m.unreadPushed = false
+	m.loadingOlder = false
+	m.Outbox = store.NewMemoryOutbox()
+	m.written = make(map[string]bool)
+	m.unverified = make(map[string]bool)
+	m.flushing = false
+	m.refreshUnsupported = false
+
+	m.CurrentRoom = ""
+	m.Cursor = 0
+	m.ResumeRoom = ""
+	m.WarningMsg = ""
+	m.TokenExpiry = time.Time{}
+	m.ChatInput.Reset()
+	m.Viewport.SetContent("")
+
+	// Volta ao login do mesmo perfil
+	m.useProfile(m.Profile)
+}
@@ -40,6 +40,12 @@ var ErrorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true)
 
 var SuccessStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true) // Verde para sucesso
 
+var WarningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Bold(true) // Amarelo para avisos
+
+var NoticeStyle = lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("240")) // Linhas de sistema no chat
+
+var OnlineStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("10")) // Indicador de presença
+
 // Estilos para a Lista de Salas
 var ListHeaderStyle = lipgloss.NewStyle().
 	Foreground(lipgloss.Color("240")).
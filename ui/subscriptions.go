@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"context"
+	"log"
+
+	"github.com/mellojp/chatli/api"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// subscriptionCmd envia a inscrição (ou o cancelamento) ao servidor. Falhas só são registradas:
+// se a conexão caiu, a reconexão refaz a inscrição da sala aberta.
+func subscriptionCmd(conn *api.Conn, subscribe bool, roomIds ...string) tea.Cmd {
+	if conn == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), api.DefaultTimeout)
+		defer cancel()
+		var err error
+		if subscribe {
+			err = conn.Subscribe(ctx, roomIds...)
+		} else {
+			err = conn.Unsubscribe(ctx, roomIds...)
+		}
+		if err != nil {
+			log.Printf("websocket: inscrição em %v falhou: %v", roomIds, err)
+		}
+		return nil
+	}
+}
+
+// subscribeCurrent inscreve uma conexão nova apenas na sala aberta (ou em nenhuma, fora do chat),
+// para que as demais salas cheguem só como contagem de não lidas. Os marcadores de leitura vão
+// junto: o servidor conta as não lidas a partir deles e as envia como room.unread (servidores
+// que não fazem isso caem na contagem local de unreadFallback).
+func (m *Model) subscribeCurrent() tea.Cmd {
+	conn := m.WSConn
+	if conn == nil {
+		return nil
+	}
+	var roomIds []string
+	if m.State == chatView && m.CurrentRoom != "" {
+		roomIds = append(roomIds, m.CurrentRoom)
+	}
+	read := m.ReadMarkers.Times()
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), api.DefaultTimeout)
+		defer cancel()
+		if err := conn.SubscribeRead(ctx, read, roomIds...); err != nil {
+			log.Printf("websocket: inscrição em %v falhou: %v", roomIds, err)
+		}
+		return nil
+	}
+}
+
+// enterRoom abre a sala no chat, passa a receber os eventos dela e carrega o histórico.
+// O histórico só é pedido depois que a inscrição foi escrita no socket, para que uma mensagem
+// nova não fique entre a página carregada e o início dos eventos da sala.
+func (m *Model) enterRoom(roomId string) tea.Cmd {
+	m.CurrentRoom = roomId
+	m.State = chatView
+	markCmd := m.markRead(roomId)
+	m.Viewport.SetContent(RenderChatView(m))
+	m.Viewport.GotoBottom()
+	m.ChatInput.Focus()
+
+	ctx, req := m.startRequest()
+	return tea.Batch(
+		tea.Sequence(
+			subscriptionCmd(m.WSConn, true, roomId),
+			loadHistoryCmd(ctx, req, m.client(), roomId),
+		),
+		m.Spinner.Tick,
+		markCmd,
+	)
+}
+
+// leaveRoom volta à lista de salas e deixa de receber os eventos da sala. O histórico em
+// memória é descartado, já que deixaria de ser atualizado; ele é recarregado ao voltar.
+func (m *Model) leaveRoom() tea.Cmd {
+	roomId := m.CurrentRoom
+	m.State = roomListView
+	if roomId == "" {
+		return nil
+	}
+	delete(m.ChatsHistory, roomId)
+	delete(m.hasMore, roomId)
+	delete(m.notices, roomId)
+	delete(m.typing, roomId)
+	// Uma página anterior ainda a caminho é descartada ao chegar
+	m.loadingOlder = false
+	return subscriptionCmd(m.WSConn, false, roomId)
+}
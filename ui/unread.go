@@ -0,0 +1,235 @@
+package ui
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/mellojp/chatli/api"
+	"github.com/mellojp/chatli/data"
+	"github.com/mellojp/chatli/store"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// unreadFallbackDelay é quanto se espera pelo room.unread do primeiro subscribe antes de
+// concluir que o servidor não conta as não lidas e contá-las pelo histórico
+const unreadFallbackDelay = 3 * time.Second
+
+// unreadFallbackMsg dispara a contagem local se nenhum room.unread chegou até ela
+type unreadFallbackMsg struct {
+	UserId string
+}
+
+// unreadLoadedMsg traz as não lidas calculadas a partir do histórico e dos marcadores de leitura
+type unreadLoadedMsg struct {
+	UserId string
+	Rooms  []data.RoomUnread
+}
+
+// unreadFallback agenda a contagem local das não lidas para servidores sem inscrição por
+// sala, que ignoram os marcadores enviados no subscribe. Sem marcadores não há o que contar.
+func (m *Model) unreadFallback() tea.Cmd {
+	if len(m.ReadMarkers.Times()) == 0 {
+		return nil
+	}
+	userId := m.Session.UserId
+	return tea.Tick(unreadFallbackDelay, func(time.Time) tea.Msg {
+		return unreadFallbackMsg{UserId: userId}
+	})
+}
+
+// loadUnreadCmd busca a última página do histórico de cada sala e conta as mensagens
+// posteriores ao marcador de leitura. Salas cuja busca falha ficam sem contagem.
+func loadUnreadCmd(c *api.Client, s data.Session, markers *store.ReadMarkers) tea.Cmd {
+	rooms := slices.Clone(s.JoinedRooms)
+	return func() tea.Msg {
+		msg := unreadLoadedMsg{UserId: s.UserId}
+		for _, room := range rooms {
+			ctx, cancel := context.WithTimeout(context.Background(), api.DefaultTimeout)
+			messages, err := c.LoadChatMessagesPageContext(ctx, room.Id, "", historyPageSize)
+			cancel()
+			if err != nil {
+				continue
+			}
+			marker, read := markers.Get(room.Id)
+			msg.Rooms = append(msg.Rooms, countUnread(room.Id, messages, marker, read, s))
+		}
+		return msg
+	}
+}
+
+// countUnread conta as mensagens de outros usuários depois do marcador. Uma sala nunca
+// aberta neste computador não tem marcador: só a data da última mensagem é aproveitada,
+// para não marcar todo o histórico como novo no primeiro uso.
+func countUnread(roomId string, messages []data.Message, marker store.ReadMarker, read bool, s data.Session) data.RoomUnread {
+	u := data.RoomUnread{RoomId: roomId}
+	for _, msg := range messages {
+		if msg.SentAt.After(u.LastMessageAt) {
+			u.LastMessageAt = msg.SentAt
+		}
+		if !read || msg.UserId == s.UserId || msg.Id == marker.MessageId || !msg.SentAt.After(marker.SentAt) {
+			continue
+		}
+		u.Unread++
+		if msg.Mentions(s.Username) {
+			u.Mentions++
+		}
+	}
+	return u
+}
+
+// handleUnreadFallback conta as não lidas pelo histórico se o servidor não mandou nenhum
+// room.unread, e aplica o resultado da contagem
+func (m *Model) handleUnreadFallback(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case unreadFallbackMsg:
+		if msg.UserId != m.Session.UserId || m.unreadPushed {
+			return nil
+		}
+		return loadUnreadCmd(m.client(), m.Session, m.ReadMarkers)
+
+	case unreadLoadedMsg:
+		// Resultado de uma sessão anterior (logout ou troca de usuário)
+		if msg.UserId != m.Session.UserId {
+			return nil
+		}
+		for _, u := range msg.Rooms {
+			// O websocket pode já ter trazido uma contagem mais recente
+			u.Unread = max(u.Unread, m.unread[u.RoomId])
+			u.Mentions = max(u.Mentions, m.mentions[u.RoomId])
+			m.setUnread(u)
+		}
+	}
+	return nil
+}
+
+// readMarkersDelay é quanto a gravação dos marcadores de leitura espera por novas marcações
+const readMarkersDelay = 2 * time.Second
+
+// readMarkersSavedMsg traz o resultado da gravação dos marcadores de leitura
+type readMarkersSavedMsg struct {
+	Markers *store.ReadMarkers
+	Err     error
+}
+
+// openReadMarkers carrega os marcadores de leitura persistidos do usuário logado
+func (m *Model) openReadMarkers() {
+	markers, err := store.OpenReadMarkers(m.Profile.Name, m.Session.UserId)
+	if err != nil {
+		m.ErrorMsg = "Erro ao abrir marcadores de leitura: " + err.Error()
+	}
+	m.ReadMarkers = markers
+}
+
+// viewing informa se a sala está aberta no chat
+func (m *Model) viewing(roomId string) bool {
+	return m.State == chatView && m.CurrentRoom == roomId
+}
+
+// markRead move o marcador da sala para a última mensagem carregada e zera as não lidas.
+// A gravação no disco fica para saveReadMarkersCmd, agrupando as marcações de readMarkersDelay.
+func (m *Model) markRead(roomId string) tea.Cmd {
+	delete(m.unread, roomId)
+	delete(m.mentions, roomId)
+	msgs := m.roomHistory(roomId).Messages()
+	if len(msgs) == 0 {
+		return nil
+	}
+	last := msgs[len(msgs)-1]
+	m.touchRoom(roomId, last.SentAt)
+	if !m.ReadMarkers.Mark(roomId, last) || m.savingMarkers {
+		return nil
+	}
+	m.savingMarkers = true
+	markers := m.ReadMarkers
+	return tea.Tick(readMarkersDelay, func(time.Time) tea.Msg {
+		return saveReadMarkersCmd(markers)()
+	})
+}
+
+// saveReadMarkersCmd grava os marcadores de leitura fora do Update
+func saveReadMarkersCmd(markers *store.ReadMarkers) tea.Cmd {
+	return func() tea.Msg {
+		return readMarkersSavedMsg{Markers: markers, Err: markers.Save()}
+	}
+}
+
+// handleMarkersSaved libera a próxima gravação e regrava o que foi marcado durante esta.
+// Marcadores de uma sessão já encerrada são gravados, mas não afetam a atual.
+func (m *Model) handleMarkersSaved(msg readMarkersSavedMsg) tea.Cmd {
+	if msg.Markers != m.ReadMarkers {
+		if msg.Err == nil && msg.Markers.Dirty() {
+			return saveReadMarkersCmd(msg.Markers)
+		}
+		return nil
+	}
+	m.savingMarkers = false
+	if msg.Err != nil {
+		m.ErrorMsg = "Erro ao salvar marcador de leitura: " + msg.Err.Error()
+		return nil
+	}
+	if m.ReadMarkers.Dirty() {
+		m.savingMarkers = true
+		return saveReadMarkersCmd(m.ReadMarkers)
+	}
+	return nil
+}
+
+// countIncoming soma às não lidas uma mensagem nova de uma sala que não está aberta
+// (servidores sem inscrição por sala mandam as mensagens de todas as salas)
+func (m *Model) countIncoming(msg data.Message) {
+	if msg.UserId == m.Session.UserId {
+		return
+	}
+	m.unread[msg.RoomId]++
+	if msg.Mentions(m.Session.Username) {
+		m.mentions[msg.RoomId]++
+	}
+}
+
+// setUnread aplica a contagem de uma sala vinda do servidor ou do cálculo local
+func (m *Model) setUnread(u data.RoomUnread) {
+	if m.viewing(u.RoomId) {
+		return
+	}
+	m.unread[u.RoomId] = u.Unread
+	m.mentions[u.RoomId] = u.Mentions
+	m.touchRoom(u.RoomId, u.LastMessageAt)
+}
+
+// touchRoom registra atividade na sala, mantendo o cursor da lista na mesma sala
+// mesmo que a ordem mude
+func (m *Model) touchRoom(roomId string, at time.Time) {
+	if !at.After(m.activity[roomId]) {
+		return
+	}
+	selected := ""
+	if rooms := m.sortedRooms(); m.Cursor < len(rooms) {
+		selected = rooms[m.Cursor].Id
+	}
+	m.activity[roomId] = at
+	for i, r := range m.sortedRooms() {
+		if r.Id == selected {
+			m.Cursor = i
+		}
+	}
+}
+
+// lastActivity retorna o horário da última mensagem conhecida da sala ou, sem mensagens,
+// o da criação
+func (m *Model) lastActivity(room data.Room) time.Time {
+	if at, ok := m.activity[room.Id]; ok {
+		return at
+	}
+	return room.CreatedAt
+}
+
+// sortedRooms retorna as salas do usuário da mais para a menos ativa
+func (m *Model) sortedRooms() []data.Room {
+	rooms := slices.Clone(m.Session.JoinedRooms)
+	slices.SortStableFunc(rooms, func(a, b data.Room) int {
+		return m.lastActivity(b).Compare(m.lastActivity(a))
+	})
+	return rooms
+}
@@ -0,0 +1,50 @@
+package ui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mellojp/chatli/config"
+	"github.com/mellojp/chatli/data"
+)
+
+// TestUnreadFallback confere a contagem pelo histórico quando o servidor não manda room.unread
+func TestUnreadFallback(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	history := []data.Message{
+		{Id: "1", RoomId: "sala", UserId: "2", Content: "lida", SentAt: base},
+		{Id: "2", RoomId: "sala", UserId: "2", Content: "oi @alice", SentAt: base.Add(time.Minute)},
+		{Id: "3", RoomId: "sala", UserId: "1", Content: "minha", SentAt: base.Add(2 * time.Minute)},
+		{Id: "4", RoomId: "sala", UserId: "2", Content: "nova", SentAt: base.Add(3 * time.Minute)},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(history)
+	}))
+	defer ts.Close()
+
+	m := NewModel(&config.Config{}, &config.Profile{APIURL: ts.URL})
+	m.Session = data.Session{Token: "token", UserId: "1", Username: "alice", JoinedRooms: []data.Room{{Id: "sala"}}}
+	m.State = roomListView
+	m.ReadMarkers.Mark("sala", history[0])
+
+	if m.unreadFallback() == nil {
+		t.Fatal("com marcadores, a contagem local deveria ser agendada")
+	}
+	_, cmd := m.Update(unreadFallbackMsg{UserId: "1"})
+	if cmd == nil {
+		t.Fatal("sem room.unread, a contagem local não foi feita")
+	}
+	m.Update(cmd())
+	if m.unread["sala"] != 2 || m.mentions["sala"] != 1 {
+		t.Errorf("não lidas %d, menções %d; esperado 2 e 1", m.unread["sala"], m.mentions["sala"])
+	}
+
+	// Com o push do servidor, a contagem local não é necessária
+	m.unreadPushed = true
+	if _, cmd := m.Update(unreadFallbackMsg{UserId: "1"}); cmd != nil {
+		t.Error("contagem local feita apesar do room.unread do servidor")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/mellojp/chatli/data"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -21,8 +23,44 @@ func renderAsciiHeader(m *Model) string {
 	return lipgloss.PlaceHorizontal(m.WindowWidth, lipgloss.Center, SystemStyle.Render(ascii)) + "\n\n"
 }
 
+// renderLoading mostra o spinner enquanto uma requisição está em andamento
+func renderLoading(m *Model) string {
+	return m.Spinner.View() + HelpStyle.Render(" loading… [esc] cancel")
+}
+
+// renderProfileLine mostra o perfil em uso nas telas de login e registro
+func renderProfileLine(m *Model) string {
+	if m.Profile.Name == "" {
+		return ""
+	}
+	return HelpStyle.Render("profile: "+m.Profile.Name+" ("+m.Client.BaseURL+")") + "\n\n"
+}
+
+func RenderProfiles(m *Model) string {
+	s := SystemStyle.Render("user@terminal:~$ ") + HighlightTitleStyle.Render("profile") + "\n\n"
+
+	for i, name := range m.Config.Names() {
+		p, _ := m.Config.Profile(name)
+		line := fmt.Sprintf("%-20s %s", name, p.APIURL)
+		if i == m.Cursor {
+			s += ListSelectedRowStyle.Copy().Width(m.WindowWidth).Render("> "+line) + "\n"
+		} else {
+			s += ListNormalRowStyle.Copy().Width(m.WindowWidth).Render("  "+line) + "\n"
+		}
+	}
+
+	s += "\n" + lipgloss.PlaceHorizontal(m.WindowWidth, lipgloss.Center, HelpStyle.Render("[up/down] nav | [enter] select profile"))
+
+	if m.ErrorMsg != "" {
+		s += "\n\n" + ErrorStyle.Render("error: "+m.ErrorMsg)
+	}
+
+	return renderAsciiHeader(m) + s
+}
+
 func RenderLogin(m *Model) string {
 	s := SystemStyle.Render("user@terminal:~$ ") + HighlightTitleStyle.Render("login") + "\n\n"
+	s += renderProfileLine(m)
 
 	if m.SuccessMsg != "" {
 		s += SuccessStyle.Render(m.SuccessMsg) + "\n\n"
@@ -56,7 +94,15 @@ func RenderLogin(m *Model) string {
 	s += fmt.Sprintf("%s%s %s\n", userPrefix, userLabel, m.UsernameInput.View())
 	s += fmt.Sprintf("%s%s %s\n\n", passPrefix, passLabel, m.PasswordInput.View())
 
-	s += lipgloss.PlaceHorizontal(m.WindowWidth, lipgloss.Center, HelpStyle.Render("[tab/arrows] switch fields | [enter] login | [esc] register new account"))
+	help := "[tab/arrows] switch fields | [enter] login | [esc] register new account"
+	if len(m.Config.Profiles) > 1 {
+		help += " | [ctrl+p] switch profile"
+	}
+	s += lipgloss.PlaceHorizontal(m.WindowWidth, lipgloss.Center, HelpStyle.Render(help))
+
+	if m.pending() {
+		s += "\n\n" + renderLoading(m)
+	}
 
 	if m.ErrorMsg != "" {
 		s += "\n\n" + ErrorStyle.Render("error: "+m.ErrorMsg)
@@ -67,6 +113,7 @@ func RenderLogin(m *Model) string {
 
 func RenderRegister(m *Model) string {
 	s := SystemStyle.Render("user@terminal:~$ ") + HighlightTitleStyle.Render("register") + "\n\n"
+	s += renderProfileLine(m)
 
 	var userPrefix, passPrefix, userLabel, passLabel string
 
@@ -91,6 +138,10 @@ func RenderRegister(m *Model) string {
 
 	s += lipgloss.PlaceHorizontal(m.WindowWidth, lipgloss.Center, HelpStyle.Render("[tab/arrows] switch fields | [enter] create account | [esc] back to login"))
 
+	if m.pending() {
+		s += "\n\n" + renderLoading(m)
+	}
+
 	if m.ErrorMsg != "" {
 		s += "\n\n" + ErrorStyle.Render("error: "+m.ErrorMsg)
 	}
@@ -107,7 +158,7 @@ func RenderRoomsList(m *Model) string {
 		width = 0
 	}
 	
-	// Layout: [Prefix 2] [Name Dynamic] [Gap 1] [Date 15] [Gap 1] [ID 36]
+	// Layout: [Prefix 2] [Name Dynamic] [Gap 1] [Activity 15] [Gap 1] [ID 36]
 	// Total Fixed = 2 + 1 + 15 + 1 + 36 = 55 chars
 	// Name Width = WindowWidth - 55
 	
@@ -120,7 +171,7 @@ func RenderRoomsList(m *Model) string {
 	// Cabeçalho
 	// Usamos estilos para forçar largura
 	nameHeader := lipgloss.NewStyle().Width(nameWidth).Render("NAME")
-	dateHeader := lipgloss.NewStyle().Width(15).Render("CREATED")
+	dateHeader := lipgloss.NewStyle().Width(15).Render("LAST ACTIVITY")
 	idHeader := lipgloss.NewStyle().Width(36).Render("ID")
 	
 	headerStr := fmt.Sprintf("  %s %s %s", nameHeader, dateHeader, idHeader)
@@ -130,14 +181,25 @@ func RenderRoomsList(m *Model) string {
 		s += HelpStyle.Render("\n  (empty directory - use 'n' to create a room)") + "\n"
 	}
 
-	for i, room := range m.Session.JoinedRooms {
-		dateStr := room.CreatedAt.Format("02/01 15:04")
-		
-		// Trunca nome se necessário (embora lipgloss oculte, é bom cortar)
+	// Salas com mensagens mais recentes primeiro
+	for i, room := range m.sortedRooms() {
+		dateStr := m.lastActivity(room).Local().Format("02/01 15:04")
+
+		// Contador de não lidas e, se alguém citou o usuário, o badge de menções
+		var badges string
+		if n := m.unread[room.Id]; n > 0 {
+			badges += fmt.Sprintf(" (%d)", n)
+		}
+		if n := m.mentions[room.Id]; n > 0 {
+			badges += fmt.Sprintf(" @%d", n)
+		}
+
+		// Trunca nome se necessário (embora lipgloss oculte, é bom cortar), preservando os badges
 		name := room.Name
-		if len(name) > nameWidth {
-			name = name[:nameWidth-1] + "…"
+		if limit := max(nameWidth-len(badges), 2); len(name) > limit {
+			name = name[:limit-1] + "…"
 		}
+		name += badges
 
 		// Renderiza colunas
 		colName := lipgloss.NewStyle().Width(nameWidth).Render(name)
@@ -161,7 +223,15 @@ func RenderRoomsList(m *Model) string {
 	// Footer de ajuda (Centralizado)
 	helpText := "[up/down] nav | [n] new room | [e] enter room id | [enter] select | [esc] logout"
 	s += "\n" + lipgloss.PlaceHorizontal(width, lipgloss.Center, HelpStyle.Render(helpText))
+
+	if m.WarningMsg != "" {
+		s += "\n\n" + WarningStyle.Render("warning: "+m.WarningMsg)
+	}
 	
+	if m.pending() {
+		s += "\n\n" + renderLoading(m)
+	}
+
 	if m.ErrorMsg != "" {
 		s += "\n\n" + ErrorStyle.Render("error: "+m.ErrorMsg)
 	}
@@ -180,6 +250,10 @@ func RenderCreateRoom(m *Model) string {
 
 	s += "\n\n" + lipgloss.PlaceHorizontal(m.WindowWidth, lipgloss.Center, HelpStyle.Render("[enter] create | [esc] cancel"))
 
+	if m.pending() {
+		s += "\n\n" + renderLoading(m)
+	}
+
 	if m.ErrorMsg != "" {
 		s += "\n\n" + ErrorStyle.Render("error: "+m.ErrorMsg)
 	}
@@ -198,6 +272,10 @@ func RenderJoinRoom(m *Model) string {
 
 	s += "\n\n" + lipgloss.PlaceHorizontal(m.WindowWidth, lipgloss.Center, HelpStyle.Render("[enter] join | [esc] cancel"))
 
+	if m.pending() {
+		s += "\n\n" + renderLoading(m)
+	}
+
 	if m.ErrorMsg != "" {
 		s += "\n\n" + ErrorStyle.Render("error: "+m.ErrorMsg)
 	}
@@ -209,36 +287,66 @@ func RenderChatView(m *Model) string {
 	var b strings.Builder
 	renderWidth := m.WindowWidth - 4 // Margem de segurança
 
-	for _, val := range m.ChatsHistory[m.CurrentRoom] {
+	// Linhas de sistema são intercaladas com as mensagens pelo horário
+	notices := m.notices[m.CurrentRoom]
+	for _, val := range m.ChatsHistory[m.CurrentRoom].Messages() {
+		for len(notices) > 0 && notices[0].SentAt.Before(val.SentAt) {
+			b.WriteString(renderNotice(notices[0], renderWidth) + "\n")
+			notices = notices[1:]
+		}
 		if val.Content == "" {
 			continue
 		}
+		b.WriteString(renderMessage(m, val, false, renderWidth) + "\n")
+	}
+	for _, val := range notices {
+		b.WriteString(renderNotice(val, renderWidth) + "\n")
+	}
 
-		displayTime := val.SentAt.Format("15:04")
+	// Mensagens ainda não confirmadas pelo servidor aparecem no fim como pendentes
+	for _, val := range m.Outbox.Pending(m.CurrentRoom) {
+		b.WriteString(renderMessage(m, val, true, renderWidth) + "\n")
+	}
+	return b.String()
+}
 
-		var line string
-		var alignedLine string
+// renderNotice desenha uma linha de sistema (entrada na sala, aviso do servidor, ...)
+func renderNotice(val data.Message, renderWidth int) string {
+	line := fmt.Sprintf("[%s] * %s", val.SentAt.Format("15:04"), val.Content)
+	return NoticeStyle.Width(renderWidth).Align(lipgloss.Center).Render(line)
+}
 
-		if val.UserId == m.Session.UserId {
-			// Mensagem do próprio usuário (Direita)
-			senderName := "Você"
-			styledUser := RoomTitleStyle.Render(senderName) // Verde (cor 2)
+func renderMessage(m *Model, val data.Message, pending bool, renderWidth int) string {
+	displayTime := val.SentAt.Format("15:04")
+	if pending {
+		displayTime = "pending"
+	}
+	content := val.Content
+	if val.EditedAt != nil {
+		content += TimeStyle.Render(" (edited)")
+	}
 
-			line = fmt.Sprintf("%s <%s> [%s]", val.Content, styledUser, TimeStyle.Render(displayTime))
-			alignedLine = lipgloss.NewStyle().Width(renderWidth).Align(lipgloss.Right).Render(line)
-		} else {
-			// Mensagem de outros (Esquerda)
-			senderName := val.SenderUsername
-			if senderName == "" {
-				senderName = val.UserId // Fallback se não tiver username
-			}
-			styledUser := SenderStyle.Render(senderName) // Verde escuro (cor 22)
-
-			line = fmt.Sprintf("[%s] <%s> %s", TimeStyle.Render(displayTime), styledUser, val.Content)
-			alignedLine = lipgloss.NewStyle().Width(renderWidth).Align(lipgloss.Left).Render(line)
-		}
+	var line string
 
-		b.WriteString(alignedLine + "\n")
+	if val.UserId == m.Session.UserId {
+		// Mensagem do próprio usuário (Direita)
+		senderName := "Você"
+		styledUser := RoomTitleStyle.Render(senderName) // Verde (cor 2)
+
+		line = fmt.Sprintf("%s <%s> [%s]", content, styledUser, TimeStyle.Render(displayTime))
+		return lipgloss.NewStyle().Width(renderWidth).Align(lipgloss.Right).Render(line)
 	}
-	return b.String()
+
+	// Mensagem de outros (Esquerda)
+	senderName := val.SenderUsername
+	if senderName == "" {
+		senderName = val.UserId // Fallback se não tiver username
+	}
+	styledUser := SenderStyle.Render(senderName) // Verde escuro (cor 22)
+	if m.presence[val.UserId] == data.PresenceOnline {
+		styledUser += OnlineStyle.Render("●")
+	}
+
+	line = fmt.Sprintf("[%s] <%s> %s", TimeStyle.Render(displayTime), styledUser, content)
+	return lipgloss.NewStyle().Width(renderWidth).Align(lipgloss.Left).Render(line)
 }